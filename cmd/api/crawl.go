@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/agent"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+	"go.uber.org/zap"
+)
+
+// newAgent creates an agent crawling with the llm and page using the settings of the config.
+func newAgent(logger *zap.Logger, cfg *config.Config, st *store.Store, llm llms.Plugin, page scraper.ScraperPage) *agent.Agent {
+	return agent.New(llm, page,
+		agent.WithLogger(logger),
+		agent.WithStore(st),
+		agent.WithLimit(cfg.Limit),
+		agent.WithExtractionMode(cfg.ExtractionMode),
+		agent.WithContentFormat(scraper.Format(cfg.ContentFormat)),
+		agent.WithVisionMode(cfg.VisionMode),
+		agent.WithTraceDir(cfg.TraceDir),
+		agent.WithAllowedDomains(append(slices.Clone(agent.BookingPlatforms), cfg.AllowedDomains...)),
+	)
+}
+
+// crawlProvider crawls a single escape room provider and returns its output row template and rooms.
+func crawlProvider(ctx context.Context, a *agent.Agent, index int, room EscapeRoom) (output.Information, []llms.Room, error) {
+	result, err := a.Run(ctx, agent.Provider{Name: room.Name, URL: room.URL})
+	if result == nil {
+		return output.Information{ID: index, ProviderName: room.Name, ProviderURL: room.URL}, nil, err
+	}
+
+	return resultInformation(index, result), result.Rooms, err
+}
+
+func resultInformation(index int, result *agent.Result) output.Information {
+	return output.Information{
+		ID:                   index,
+		LLM:                  result.Model,
+		LLMDuration:          result.LLMDuration,
+		RequestDuration:      result.RequestDuration,
+		WebsitesChecked:      result.WebsitesChecked,
+		WebsiteMaxLength:     result.WebsiteMaxLength,
+		WebsiteReducedLength: result.WebsiteReducedLength,
+		ContentFormat:        result.ContentFormat,
+		VisionMode:           result.VisionMode,
+		ProviderURL:          result.ProviderURL,
+		ProviderName:         result.ProviderName,
+		TokenLimitReached:    result.TokenLimitReached,
+		TokenCount:           result.TokenCount,
+		ContextCompactions:   result.ContextCompactions,
+		ChunksExtracted:      result.ChunksExtracted,
+		ConsentBanners:       result.ConsentBanners,
+		InvalidURLs:          result.InvalidURLs,
+		ToolRetries:          result.ToolRetries,
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/evaluation"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+)
+
+// evaluate scores every CSV of the runs against the ground truth and prints a comparison table.
+func evaluate(w io.Writer, cfg *config.EvalCmd) error {
+	truth, err := evaluation.LoadGroundTruth(cfg.GroundTruth)
+	if err != nil {
+		return fmt.Errorf("failed to load ground truth: %w", err)
+	}
+
+	files := cfg.Files
+	if len(files) == 0 {
+		files, err = filepath.Glob(filepath.Join(cfg.Dir, "*.csv"))
+		if err != nil {
+			return fmt.Errorf("failed to list csv files: %w", err)
+		}
+	}
+
+	results := make([]evaluation.Result, 0, len(files))
+	for _, file := range files {
+		information, err := output.ReadCSVFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		mode, model := evaluation.ParseRunName(file)
+		results = append(results, evaluation.Evaluate(mode, model, information, truth))
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Model != results[j].Model {
+			return results[i].Model < results[j].Model
+		}
+
+		return results[i].Mode < results[j].Mode
+	})
+
+	return printResults(w, results)
+}
+
+func printResults(w io.Writer, results []evaluation.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Model\tMode\tProviders\tRooms\tFound\tMatched\tPrecision\tRecall\tPlayers\tDuration\tGenre\tDifficulty\tValid URLs\tTokens\tLLM Duration\tRequest Duration\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t\n",
+			r.Model, r.Mode, r.Providers, r.ExpectedRooms, r.FoundRooms, r.MatchedRooms,
+			percent(r.Precision), percent(r.Recall),
+			percent(r.PlayersAccuracy), percent(r.DurationAccuracy), percent(r.GenreAccuracy), percent(r.DifficultyAccuracy),
+			percent(r.URLValidity), r.TokenCount, r.LLMDuration.Round(1e6), r.RequestDuration.Round(1e6))
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to print results: %w", err)
+	}
+
+	return nil
+}
+
+func percent(value float64) string {
+	if math.IsNaN(value) {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.1f%%", value*100)
+}
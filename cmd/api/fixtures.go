@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"cloud.google.com/go/vertexai/genai"
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/pkg/httpreplay"
+	"google.golang.org/api/option"
+)
+
+// fixtures records the HTTP interactions of the llm clients or replays them from a file.
+type fixtures struct {
+	client     *http.Client
+	recorder   *httpreplay.Recorder
+	recordFile string
+	// standInURL is the URL of a local server replaying the interactions for clients without an http client option.
+	standInURL string
+	standIn    *http.Server
+}
+
+func newFixtures(cfg *config.Config) (*fixtures, error) {
+	f := &fixtures{
+		client: &http.Client{},
+	}
+
+	switch {
+	case cfg.HTTPRecord != "":
+		f.recorder = httpreplay.NewRecorder(nil, httpreplay.WithFile(cfg.HTTPRecord))
+		f.recordFile = cfg.HTTPRecord
+		f.client.Transport = f.recorder
+	case cfg.HTTPReplay != "":
+		replayer, err := httpreplay.Load(cfg.HTTPReplay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load http fixtures: %w", err)
+		}
+		f.client.Transport = replayer
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for stand-in server: %w", err)
+		}
+		f.standIn = &http.Server{Handler: replayer.Handler()}
+		f.standInURL = "http://" + listener.Addr().String()
+		go func() {
+			_ = f.standIn.Serve(listener)
+		}()
+	}
+
+	return f, nil
+}
+
+// vertexOptions returns the options of the Vertex AI client. When replaying, the client talks REST
+// through the replaying http client, which sends no credentials, so none are looked up.
+func (f *fixtures) vertexOptions(endpoint string) []option.ClientOption {
+	if f.standIn != nil {
+		return []option.ClientOption{genai.WithREST(), option.WithHTTPClient(f.client)}
+	}
+
+	opts := []option.ClientOption{}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	return opts
+}
+
+// baseURL returns the configured base URL or, when replaying, the URL of the stand-in server.
+func (f *fixtures) baseURL(configured string) string {
+	if configured != "" || f.standIn == nil {
+		return configured
+	}
+
+	return f.standInURL
+}
+
+// Close saves the recorded interactions, which were saved after every interaction too, and stops the stand-in server.
+func (f *fixtures) Close() error {
+	var err error
+	if f.recorder != nil {
+		err = f.recorder.Save(f.recordFile)
+	}
+	if f.standIn != nil {
+		err = errors.Join(err, f.standIn.Close())
+	}
+
+	return err
+}
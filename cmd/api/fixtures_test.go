@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/vertexai/genai"
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+)
+
+func TestFixturesReplayVertexWithoutCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(dir, "missing.json"))
+	fixturesFile := filepath.Join(dir, "fixtures.json")
+	err := os.WriteFile(fixturesFile, []byte("[]"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing fixtures: %v", err)
+	}
+
+	fx, err := newFixtures(&config.Config{HTTPReplay: fixturesFile})
+	if err != nil {
+		t.Fatalf("Error creating fixtures: %v", err)
+	}
+	defer fx.Close()
+
+	client, err := genai.NewClient(context.Background(), "project", "europe-west3", fx.vertexOptions("")...)
+	if err != nil {
+		t.Fatalf("Expected the replayed client to need no credentials, got %v", err)
+	}
+	client.Close()
+}
@@ -1,20 +1,56 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
 )
 
 type Config struct {
-	GCloudProjectID  string `arg:"--listen,env:GCLOUDPROJECTID"`
-	GCloudLocationID string `arg:"--listen,env:GCLOUDLOCATIONID"`
-	ChatGPTToken     string `arg:"--listen,env:CHATGPTTOKEN"`
-	TogetherAIToken  string `arg:"--listen,env:TOGETHERAITOKEN"`
-	ClaudeToken      string `arg:"--listen,env:CLAUDETOKEN"`
-	MistralToken     string `arg:"--listen,env:MISTRALTOKEN"`
-	JambaToken       string `arg:"--listen,env:JAMBATOKEN"`
+	GCloudProjectID  string `arg:"--gcloud-project-id,env:GCLOUDPROJECTID"`
+	GCloudLocationID string `arg:"--gcloud-location-id,env:GCLOUDLOCATIONID"`
+	ChatGPTToken     string `arg:"--chatgpt-token,env:CHATGPTTOKEN"`
+	TogetherAIToken  string `arg:"--togetherai-token,env:TOGETHERAITOKEN"`
+	ClaudeToken      string `arg:"--claude-token,env:CLAUDETOKEN"`
+	MistralToken     string `arg:"--mistral-token,env:MISTRALTOKEN"`
+	JambaToken       string `arg:"--jamba-token,env:JAMBATOKEN"`
 	Limit            int    `arg:"--limit,env:LIMIT"`
+	RoomsFile        string `arg:"--rooms-file,env:ROOMSFILE" help:"JSON file of the escape room providers to crawl"`
+	OutputDir        string `arg:"--output-dir,env:OUTPUTDIR" help:"directory of the output CSVs"`
+	StoreDir         string `arg:"--store-dir,env:STOREDIR" help:"directory of the job and crawl state store"`
+	ExtractionMode   string `arg:"--extraction-mode,env:EXTRACTIONMODE" help:"conversation or chunked"`
+	ContentFormat    string `arg:"--content-format,env:CONTENTFORMAT" help:"page content sent to the llm: html, markdown or text"`
+	VisionMode       string `arg:"--vision-mode,env:VISIONMODE" help:"full-page screenshots sent to llms with image support: off, alongside or instead of the page content"`
+	GPTStrict        bool   `arg:"--gpt-strict,env:GPTSTRICT" help:"use strict structured outputs with the gpt models, their runs are named <model>-strict"`
+
+	OpenAIBaseURL     string `arg:"--openai-base-url,env:OPENAIBASEURL"`
+	TogetherAIBaseURL string `arg:"--togetherai-base-url,env:TOGETHERAIBASEURL"`
+	JambaBaseURL      string `arg:"--jamba-base-url,env:JAMBABASEURL"`
+	ClaudeBaseURL     string `arg:"--claude-base-url,env:CLAUDEBASEURL"`
+	MistralBaseURL    string `arg:"--mistral-base-url,env:MISTRALBASEURL"`
+	VertexEndpoint    string `arg:"--vertex-endpoint,env:VERTEXENDPOINT" help:"gRPC endpoint of Vertex AI, its requests can't be recorded"`
+	HTTPRecord        string `arg:"--http-record,env:HTTPRECORD" help:"file the HTTP interactions of the llm clients are recorded to"`
+	HTTPReplay        string `arg:"--http-replay,env:HTTPREPLAY" help:"file of recorded HTTP interactions replayed instead of calling the llm APIs"`
+	ArchiveDir        string `arg:"--archive-dir,env:ARCHIVEDIR" help:"directory every scraped page is archived to"`
+	ReplayArchive     string `arg:"--replay-archive,env:REPLAYARCHIVE" help:"directory of an archive the pages are served from instead of a live browser"`
+	TraceDir          string `arg:"--trace-dir,env:TRACEDIR" help:"directory a JSONL trace of every provider run is written to"`
+
+	Backend         string        `arg:"--backend,env:BACKEND" help:"how pages are loaded: chrome, http or auto, which uses http and chrome for javascript rendered pages"`
+	UserAgent       string        `arg:"--user-agent,env:USERAGENT" help:"user agent of the browser if stealth is disabled, its product token selects the robots.txt rules"`
+	NoStealth       bool          `arg:"--no-stealth,env:NOSTEALTH" help:"identify as the user agent instead of hiding the automation"`
+	IgnoreRobotsTxt bool          `arg:"--ignore-robots-txt,env:IGNOREROBOTSTXT" help:"navigate to pages disallowed by robots.txt"`
+	MinDelay        time.Duration `arg:"--min-delay,env:MINDELAY" help:"minimum delay between navigations to the same host across all pages"`
+	MaxPerHost      int           `arg:"--max-per-host,env:MAXPERHOST" help:"maximum number of pages navigating the same host at once"`
+	PoolSize        int           `arg:"--pool-size,env:POOLSIZE" help:"maximum number of browser pages, each crawls one provider with one model at a time"`
+	AllowedDomains  []string      `arg:"--allowed-domains,env:ALLOWEDDOMAINS" help:"domains besides the known booking platforms the llm may navigate to outside of the provider website"`
+	ConsentRules    string        `arg:"--consent-rules,env:CONSENTRULES" help:"JSON file of the rules cookie consent banners are dismissed with instead of the built-in ones"`
+	LoginRecipes    string        `arg:"--login-recipes,env:LOGINRECIPES" help:"JSON file of the login forms of websites which need authentication, filled in with the login credentials"`
+	SessionDir      string        `arg:"--session-dir,env:SESSIONDIR" help:"directory the session cookies of logins are saved to and restored from"`
 
 	ProxyServer   string
 	ProxyUsername string
@@ -22,11 +58,49 @@ type Config struct {
 	LoginEmail    string
 	LoginPassword string
 	OTPSecret     string
+
+	Serve *ServeCmd `arg:"subcommand:serve" help:"run the HTTP API server instead of a one-shot batch run"`
+	Eval  *EvalCmd  `arg:"subcommand:eval" help:"score output CSVs against a ground truth file"`
+	Trace *TraceCmd `arg:"subcommand:trace" help:"inspect the traces of provider runs"`
+}
+
+// ServeCmd holds the options of the serve subcommand.
+type ServeCmd struct {
+	Listen string `arg:"--listen,env:LISTEN" default:":8080" help:"address the HTTP server listens on"`
+}
+
+// EvalCmd holds the options of the eval subcommand.
+type EvalCmd struct {
+	GroundTruth string   `arg:"--ground-truth,env:GROUNDTRUTH" default:"./groundTruth.json" help:"JSON file of the labeled rooms per provider, see groundTruth.sample.json for the format"`
+	Dir         string   `arg:"--dir" default:"./csvs" help:"directory of the output CSVs, used if no files are given"`
+	Files       []string `arg:"positional" help:"output CSVs to evaluate"`
+}
+
+// TraceCmd holds the subcommands of the trace subcommand.
+type TraceCmd struct {
+	View *TraceViewCmd `arg:"subcommand:view" help:"render a trace file as a timeline"`
+}
+
+// TraceViewCmd holds the options of the trace view subcommand.
+type TraceViewCmd struct {
+	File string `arg:"positional,required" help:"JSONL trace file"`
+	Full bool   `arg:"--full" help:"print the prompts and tool arguments without shortening them"`
 }
 
 func New() (*Config, error) {
 	c := &Config{
-		Limit: 50,
+		Limit:          50,
+		RoomsFile:      "./escapeRooms.json",
+		OutputDir:      ".",
+		StoreDir:       "./state",
+		ExtractionMode: "conversation",
+		ContentFormat:  "html",
+		VisionMode:     "off",
+		UserAgent:      scraper.DefaultUserAgent,
+		MinDelay:       scraper.DefaultMinDelay,
+		MaxPerHost:     scraper.DefaultMaxPerHost,
+		PoolSize:       4,
+		Backend:        "chrome",
 	}
 
 	err := arg.Parse(c) // nolint:typecheck
@@ -34,5 +108,36 @@ func New() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	if c.ExtractionMode != "conversation" && c.ExtractionMode != "chunked" {
+		return nil, fmt.Errorf("unknown extraction mode: %s", c.ExtractionMode)
+	}
+
+	if c.VisionMode != "off" && c.VisionMode != "alongside" && c.VisionMode != "instead" {
+		return nil, fmt.Errorf("unknown vision mode: %s", c.VisionMode)
+	}
+
+	if c.Backend != "chrome" && c.Backend != "http" && c.Backend != "auto" {
+		return nil, fmt.Errorf("unknown backend: %s", c.Backend)
+	}
+
+	if c.HTTPRecord != "" && c.HTTPReplay != "" {
+		return nil, errors.New("http interactions can't be recorded and replayed at once")
+	}
+
+	if c.LoginRecipes != "" && c.Backend == "http" {
+		return nil, errors.New("logins need the chrome or auto backend")
+	}
+
+	if c.ArchiveDir != "" && c.ReplayArchive != "" {
+		return nil, errors.New("pages can't be archived and replayed at once")
+	}
+
+	if c.Eval != nil {
+		_, err = os.Stat(c.Eval.GroundTruth)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("ground truth file %s not found, set it with --ground-truth, see groundTruth.sample.json for the format", c.Eval.GroundTruth)
+		}
+	}
+
 	return c, nil
 }
@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"slices"
-	"sync"
-	"time"
 
 	"cloud.google.com/go/vertexai/genai"
 	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/identity"
 	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
 	"github.com/martinbockt/esc-llm-webscraper/internal/llms/gpt"
 	"github.com/martinbockt/esc-llm-webscraper/internal/llms/jamba"
@@ -18,9 +18,12 @@ import (
 	"github.com/martinbockt/esc-llm-webscraper/internal/llms/vertex"
 	"github.com/martinbockt/esc-llm-webscraper/internal/output"
 	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
 	"github.com/martinbockt/esc-llm-webscraper/pkg/jambaClient"
 	"github.com/martinbockt/esc-llm-webscraper/pkg/togetherai"
 	openai "github.com/sashabaranov/go-openai"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	mistralSDK "github.com/tmc/langchaingo/llms/mistral"
 	"go.uber.org/zap"
 )
 
@@ -33,25 +36,141 @@ func main() {
 	if err != nil {
 		logger.Fatal("failed to load config", zap.Error(err))
 	}
+	if cfg.Eval != nil {
+		err = evaluate(os.Stdout, cfg.Eval)
+		if err != nil {
+			logger.Fatal("failed to evaluate", zap.Error(err))
+		}
+
+		return
+	}
+	if cfg.Trace != nil {
+		if cfg.Trace.View == nil {
+			logger.Fatal("missing trace subcommand, use trace view")
+		}
+		err = viewTrace(os.Stdout, cfg.Trace.View)
+		if err != nil {
+			logger.Fatal("failed to view trace", zap.Error(err))
+		}
+
+		return
+	}
+
+	fx, err := newFixtures(cfg)
+	if err != nil {
+		logger.Fatal("failed to init http fixtures", zap.Error(err))
+	}
+
 	ctx := context.Background()
-	vertexClient, err := genai.NewClient(ctx, cfg.GCloudProjectID, cfg.GCloudLocationID)
+	vertexClient, err := genai.NewClient(ctx, cfg.GCloudProjectID, cfg.GCloudLocationID, fx.vertexOptions(cfg.VertexEndpoint)...)
 	if err != nil {
 		logger.Fatal("failed to init genAI client", zap.Error(err))
 	}
-	gptClient := openai.NewClient(cfg.ChatGPTToken)
-	togetheraiClient := togetherai.NewChatService(logger, cfg.TogetherAIToken)
-	jClient := jambaClient.NewChatService(logger, cfg.JambaToken)
+	gptConfig := openai.DefaultConfig(cfg.ChatGPTToken)
+	gptConfig.HTTPClient = fx.client
+	if baseURL := fx.baseURL(cfg.OpenAIBaseURL); baseURL != "" {
+		gptConfig.BaseURL = baseURL
+	}
+	gptClient := openai.NewClientWithConfig(gptConfig)
+	togetheraiClient := togetherai.NewChatService(logger, cfg.TogetherAIToken, togetherai.WithBaseURL(cfg.TogetherAIBaseURL), togetherai.WithHTTPClient(fx.client))
+	jClient := jambaClient.NewChatService(logger, cfg.JambaToken, jambaClient.WithBaseURL(cfg.JambaBaseURL), jambaClient.WithHTTPClient(fx.client))
+	claudeOpts := []anthropic.Option{anthropic.WithHTTPClient(fx.client)}
+	if cfg.ClaudeBaseURL != "" {
+		claudeOpts = append(claudeOpts, anthropic.WithBaseURL(cfg.ClaudeBaseURL))
+	}
+	mistralOpts := []mistralSDK.Option{}
+	if baseURL := fx.baseURL(cfg.MistralBaseURL); baseURL != "" {
+		mistralOpts = append(mistralOpts, mistralSDK.WithEndpoint(baseURL))
+	}
+	gptOpts := []gpt.Option{}
+	if cfg.GPTStrict {
+		gptOpts = append(gptOpts, gpt.WithStrict())
+	}
 
-	llmList := initLLMs(vertexClient, gptClient, togetheraiClient, jClient, cfg.ClaudeToken, cfg.MistralToken)
+	llmList := initLLMs(vertexClient, gptClient, togetheraiClient, jClient, cfg.ClaudeToken, cfg.MistralToken, claudeOpts, mistralOpts, gptOpts)
 
-	scraper, err := scraper.New(logger, cfg.ProxyServer, cfg.ProxyUsername, cfg.ProxyPassword, cfg.LoginEmail, cfg.LoginPassword, cfg.OTPSecret)
+	format, err := scraper.ParseFormat(cfg.ContentFormat)
+	if err != nil {
+		logger.Fatal("failed to parse content format", zap.Error(err))
+	}
+
+	browser, err := newBrowser(logger, cfg, format)
 	if err != nil {
 		logger.Fatal("failed to init scraper", zap.Error(err))
 	}
+	pool := scraper.NewPool(logger, browser, cfg.PoolSize)
+
+	st, err := store.New(cfg.StoreDir)
+	if err != nil {
+		logger.Fatal("failed to open store", zap.Error(err))
+	}
 
-	err = run(ctx, logger, cfg, llmList, scraper)
+	if cfg.Serve != nil {
+		err = serve(ctx, logger, cfg, llmList, pool, st)
+	} else {
+		err = run(ctx, logger, cfg, llmList, pool, st)
+	}
+	poolErr := pool.Close()
+	closeErr := fx.Close()
 	if err != nil {
-		logger.Fatal("failed to run", zap.Error(err))
+		logger.Fatal("failed to run", zap.Bool("serve", cfg.Serve != nil), zap.Error(err))
+	}
+	if poolErr != nil {
+		logger.Fatal("failed to close browser", zap.Error(poolErr))
+	}
+	if closeErr != nil {
+		logger.Fatal("failed to close http fixtures", zap.Error(closeErr))
+	}
+}
+
+// newBrowser creates the live browser of the configured backend or, if configured, a browser replaying an archive.
+func newBrowser(logger *zap.Logger, cfg *config.Config, format scraper.Format) (scraper.ScraperBrowser, error) {
+	if cfg.ReplayArchive != "" {
+		archive, err := scraper.OpenArchive(cfg.ReplayArchive)
+		if err != nil {
+			return nil, err
+		}
+
+		return scraper.NewReplayBrowser(archive, format), nil
+	}
+
+	opts := []scraper.Option{
+		scraper.WithUserAgent(cfg.UserAgent),
+		scraper.WithStealth(!cfg.NoStealth),
+		scraper.WithPoliteness(cfg.MinDelay, cfg.MaxPerHost),
+	}
+	if cfg.IgnoreRobotsTxt {
+		opts = append(opts, scraper.WithoutRobots())
+	}
+	if cfg.ConsentRules != "" {
+		rules, err := scraper.LoadConsentRules(cfg.ConsentRules)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, scraper.WithConsentRules(rules))
+	}
+	if cfg.LoginRecipes != "" {
+		recipes, err := scraper.LoadLoginRecipes(cfg.LoginRecipes)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, scraper.WithLoginRecipes(recipes), scraper.WithSessionDir(cfg.SessionDir))
+	}
+	if cfg.ArchiveDir != "" {
+		archive, err := scraper.OpenArchive(cfg.ArchiveDir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, scraper.WithArchive(archive))
+	}
+
+	switch cfg.Backend {
+	case "http":
+		return scraper.NewHTTPBrowser(logger, format, cfg.ProxyServer, cfg.ProxyUsername, cfg.ProxyPassword, opts...)
+	case "auto":
+		return scraper.NewAutoBrowser(logger, format, cfg.ProxyServer, cfg.ProxyUsername, cfg.ProxyPassword, cfg.LoginEmail, cfg.LoginPassword, cfg.OTPSecret, opts...)
+	default:
+		return scraper.New(logger, format, cfg.ProxyServer, cfg.ProxyUsername, cfg.ProxyPassword, cfg.LoginEmail, cfg.LoginPassword, cfg.OTPSecret, opts...)
 	}
 }
 
@@ -82,209 +201,76 @@ func newLogger() (*zap.Logger, error) {
 	return logger, nil
 }
 
-func initLLMs(vertexClient *genai.Client, gptClient *openai.Client, togetheraiClient *togetherai.ChatService, jambaClient *jambaClient.ChatService, claudeToken, mistralToken string) *llms.Registry {
+func initLLMs(vertexClient *genai.Client, gptClient *openai.Client, togetheraiClient *togetherai.ChatService, jambaClient *jambaClient.ChatService, claudeToken, mistralToken string, claudeOpts []anthropic.Option, mistralOpts []mistralSDK.Option, gptOpts []gpt.Option) *llms.Registry {
 	llmRegistry := llms.NewRegistry()
 	llmRegistry.Register(jamba.New(jambaClient, "jamba-1.5-large", 0, false))
 	llmRegistry.Register(jamba.New(jambaClient, "jamba-1.5-mini", 0, false))
 
 	// llmRegistry.Register(llama.New(togetheraiClient, "meta-llama/Meta-Llama-3.1-8B-Instruct-Turbo", 0, false))
 	// llmRegistry.Register(mistral.New("mistral-large-2407", mistralToken, false))
-	llmRegistry.Register(mistral.New("mistral-large-2407", mistralToken, false))
-	llmRegistry.Register(vertex.New(vertexClient, "gemini-1.5-flash-001", 0.5, false))
-	llmRegistry.Register(vertex.New(vertexClient, "gemini-1.5-pro-001", 0.5, false))
+	llmRegistry.Register(mistral.New("mistral-large-2407", mistralToken, false, mistralOpts...))
+	llmRegistry.Register(vertex.New(vertexClient, "gemini-1.5-flash-001", 0.5, true))
+	llmRegistry.Register(vertex.New(vertexClient, "gemini-1.5-pro-001", 0.5, true))
 
-	// llmRegistry.Register(claude.New("claude-3-5-sonnet-20240620", claudeToken, true))
-	// llmRegistry.Register(gpt.New(gptClient, openai.GPT4o, true))
-	llmRegistry.Register(gpt.New(gptClient, openai.GPT4oMini, true))
+	// llmRegistry.Register(claude.New("claude-3-5-sonnet-20240620", claudeToken, true, claudeOpts...))
+	// llmRegistry.Register(gpt.New(gptClient, openai.GPT4o, true, gptOpts...))
+	llmRegistry.Register(gpt.New(gptClient, openai.GPT4oMini, true, gptOpts...))
 
 	return llmRegistry
 }
 
-func run(ctx context.Context, logger *zap.Logger, cfg *config.Config, llmList *llms.Registry, scraperBrowser scraper.ScraperBrowser) error {
-	var errs error
-	rooms, errs := parseEscapeRooms("./escapeRooms.json")
-	if errs != nil {
-		return fmt.Errorf("failed to parse escape rooms: %w", errs)
+func run(ctx context.Context, logger *zap.Logger, cfg *config.Config, llmList *llms.Registry, pool *scraper.Pool, st *store.Store) error {
+	rooms, err := parseEscapeRooms(cfg.RoomsFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse escape rooms: %w", err)
 	}
 
-	var errorMutex sync.Mutex
-	syncGroup := sync.WaitGroup{}
-
-	for _, llm := range llmList.Plugins() {
-		syncGroup.Add(1)
-		go func() {
-			defer syncGroup.Done()
-			scraper, err := scraperBrowser.CreatePage()
-			if err != nil {
-				logger.Error("failed to create page", zap.Error(err))
+	plugins := llmList.Plugins()
+	outputs := make([]*output.Output, len(plugins))
+	existing := make([][]output.Information, len(plugins))
+	for i, llm := range plugins {
+		outputs[i] = output.New()
+		existing[i], err = outputs[i].ReadOutputCSV(filepath.Join(cfg.OutputDir, llm.ModelName()))
+		if err != nil {
+			return fmt.Errorf("failed to read existing rooms: %w", err)
+		}
+	}
 
-				return
-			}
-			op := output.New()
-			existingRooms, err := op.ReadOutputCSV(llm.ModelName())
-			if err != nil {
-				logger.Error("failed to read existing rooms", zap.Error(err))
+	// the jobs of a provider follow each other, so the models crawl different providers at once
+	jobs := []scraper.Job{}
+	for index, room := range rooms {
+		for i, llm := range plugins {
+			if slices.ContainsFunc(existing[i], func(eRoom output.Information) bool {
+				return identity.SameURL(eRoom.ProviderURL, room.URL)
+			}) {
+				logger.Info("provider already in output", zap.String("provider", room.Name), zap.String("model", llm.ModelName()))
 
-				return
+				continue
 			}
 
-			for index, room := range rooms {
-				llm.Guided(false)
-				if slices.ContainsFunc(existingRooms, func(eRoom output.Information) bool {
-					return eRoom.RoomName == room.Name
-				}) {
-					logger.Info("room already scraped", zap.String("room", room.Name))
-
-					continue
-				}
-
-				var err error
-				logger.Info("loaded llm", zap.String("name", llm.ModelName()))
-				prompt := "List all escape rooms of the website. If there is, use the Escape Room detail pages as content source."
-				rooms := []llms.Room{}
-				response := []llms.LlmResposeWithChatID{
-					{
-						UrlsResp: llms.UrlsResp{URLs: []string{room.URL}},
-						ChatID:   "",
-					},
-				}
-				var done bool
-				startTime := time.Now()
-				llmDuration := time.Duration(0)
-				var tokenCount int
-				var websiteLength, shortenedLength, websitesChecked int
-				var websiteContent []string
-				for i := range cfg.Limit {
-					done = true
-					for _, resp := range response {
-						rooms = append(rooms, resp.Rooms...)
-
-						var websiteMaxLength, shortLength int
-						if len(resp.URLs) != 0 {
-							done = false
-						} else {
-							llm.AddPrompt(nil, "added", resp.ChatID, resp.ToolName)
-
-							continue
-						}
-
-						for _, url := range resp.URLs {
-							var content string
-							err = scraper.Navigate(url)
-							if err != nil {
-								err = fmt.Errorf("failed to navigate: %w", err)
-
-								break
-							}
-
-							content, websiteMaxLength, shortLength, err = scraper.PageContent()
-							websiteLength += websiteMaxLength
-							shortenedLength += shortLength
-							if err != nil {
-								err = fmt.Errorf("failed to get page content: %w", err)
-
-								break
-							}
-
-							logger.Info("page content length", zap.Int("initial length", websiteLength), zap.Int("shortened length", shortenedLength))
-							p := fmt.Sprintf("Current URL: %s; Current website content: %s", url, content)
-							prompt += p
-							websiteContent = append(websiteContent, p)
-							websitesChecked++
-						}
-						llm.AddPrompt(nil, prompt, resp.ChatID, resp.ToolName)
-					}
-					if done || err != nil || len(response) == 0 {
-						addToOutput(op,
-							output.Information{
-								ID:                   index,
-								LLM:                  llm.ModelName(),
-								LLMDuration:          llmDuration,
-								RequestDuration:      time.Since(startTime),
-								WebsitesChecked:      websitesChecked,
-								WebsiteMaxLength:     websiteLength,
-								WebsiteReducedLength: shortenedLength,
-								ProviderURL:          room.URL,
-								ProviderName:         room.Name,
-								TokenLimitReached:    false,
-								TokenCount:           tokenCount,
-							},
-							rooms,
-							err)
-						logger.Info("done", zap.Bool("done", done), zap.Error(err))
-						llm.ResetChat()
-
-						break
+			jobs = append(jobs, scraper.Job{
+				// the plugins keep chat state, a model crawls one provider at a time
+				Key: llm.ModelName(),
+				Run: func(ctx context.Context, page scraper.ScraperPage) error {
+					llm.Guided(false)
+					info, rooms, err := crawlProvider(ctx, newAgent(logger, cfg, st, llm, page), index, room)
+					if ctx.Err() != nil {
+						// interrupted crawls are resumed from the store instead of being written as failed
+						return err
 					}
+					addToOutput(outputs[i], info, rooms, err)
 
-					result, duration, reqTokenCount, err := llm.ExecutePrompt(ctx)
-					llmDuration += duration
-					tokenCount = reqTokenCount
-					if err != nil {
-						logger.Error("failed to prompt", zap.Error(err))
-					}
-					logger.Info("result", zap.Any("result", result))
-					response = result
-					if err != nil || i == cfg.Limit {
-						llm.ResetChat()
-						tokenLimit := false
-						if len(rooms) == 0 {
-							tokenLimit = true
-							// we assume error means token limit reached
-							llm.RoomToolOnly()
-							for i, wc := range websiteContent {
-								if i == 0 {
-									continue // skip the first one / main page
-								}
-
-								llm.RoomToolOnly()
-								llm.AddPrompt(nil, wc, "", "")
-								resp, time, _, err := llm.ExecutePrompt(ctx)
-								llm.ResetChat()
-								if err != nil {
-									logger.Error("failed to execute prompt:", zap.Error(err))
-
-									break
-								}
-
-								llmDuration += time
-								for _, res := range resp {
-									rooms = append(rooms, res.Rooms...)
-								}
-							}
-						}
-						addToOutput(op,
-							output.Information{
-								ID:                   index,
-								LLM:                  llm.ModelName(),
-								LLMDuration:          llmDuration,
-								RequestDuration:      time.Since(startTime),
-								WebsitesChecked:      websitesChecked,
-								WebsiteMaxLength:     websiteLength,
-								WebsiteReducedLength: shortenedLength,
-								ProviderURL:          room.URL,
-								ProviderName:         room.Name,
-								TokenLimitReached:    tokenLimit,
-								TokenCount:           tokenCount,
-							},
-							rooms,
-							err)
-						logger.Error("failed to execute prompt", zap.Error(err), zap.Int("limit", i))
-
-						break
-					}
-				}
-				errs = errors.Join(errs, err)
-			}
-			logger.Info("rooms", zap.Any("rooms", rooms))
-			err = op.SaveAsCSV(llm.ModelName())
+					return err
+				},
+			})
+		}
+	}
 
-			errorMutex.Lock()
-			errs = errors.Join(errs, err)
-			errorMutex.Unlock()
-		}()
+	errs := pool.RunJobs(ctx, jobs)
+	for i, llm := range plugins {
+		err = outputs[i].SaveAsCSV(filepath.Join(cfg.OutputDir, llm.ModelName()))
+		errs = errors.Join(errs, err)
 	}
-	syncGroup.Wait()
 
 	return errs
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/agent"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/llmstest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper/scrapertest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	providerURL = "https://escape.example.com/"
+	gruftURL    = "https://escape.example.com/rooms/gruft/"
+	laborURL    = "https://escape.example.com/rooms/labor/"
+)
+
+var sitePages = map[string]string{
+	providerURL: `<html><body><h1>Escape Rooms</h1><a href="/rooms/gruft/">Die Gruft</a><a href="/rooms/labor/">Das Labor</a></body></html>`,
+	gruftURL:    `<html><body><h1>Die Gruft</h1><p>Horror, 2-6 Spieler, 60 Minuten</p><a href="/buchen">Buchen</a></body></html>`,
+	laborURL:    `<html><body><h1>Das Labor</h1><p>Science Fiction, 2-4 Spieler, 90 Minuten</p></body></html>`,
+}
+
+var gruft = llms.Room{Name: "Die Gruft", PlayersMin: 2, PlayersMax: 6, Duration: 60, Genre: "Horror", DetailPageURL: gruftURL, BookingURL: "/buchen"}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	roomsFile := filepath.Join(dir, "escapeRooms.json")
+	err := os.WriteFile(roomsFile, []byte(`[{"name": "Escape Example", "url": "`+providerURL+`"}]`), 0644)
+	if err != nil {
+		t.Fatalf("Error writing rooms file: %v", err)
+	}
+
+	cfg := &config.Config{
+		Limit:          5,
+		RoomsFile:      roomsFile,
+		OutputDir:      dir,
+		ExtractionMode: agent.ExtractionConversation,
+		ContentFormat:  string(scraper.FormatHTML),
+	}
+	st, err := store.New(filepath.Join(dir, "state"))
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+
+	llmList := llms.NewRegistry()
+	llmList.Register(llmstest.New("scripted",
+		llmstest.Step{URLs: []string{gruftURL}, Tokens: 1200},
+		llmstest.Step{Rooms: []llms.Room{gruft, gruft}, Tokens: 2400},
+	))
+
+	err = run(context.Background(), zap.NewNop(), cfg, llmList, scraper.NewPool(zap.NewNop(), scrapertest.NewBrowser(sitePages), 2), st)
+	if err != nil {
+		t.Fatalf("Error running: %v", err)
+	}
+
+	information, err := output.ReadCSVFile(filepath.Join(dir, "scriptedoutput.csv"))
+	if err != nil {
+		t.Fatalf("Error reading output: %v", err)
+	}
+	if len(information) != 1 {
+		t.Fatalf("Expected 1 deduplicated room, got %d", len(information))
+	}
+	if info := information[0]; info.RoomName != "Die Gruft" || info.ProviderURL != providerURL || info.TokenCount != 3600 || info.WebsitesChecked != 2 {
+		t.Errorf("Unexpected output %+v", info)
+	}
+
+	// providers in the output are skipped, the exhausted script would fail otherwise
+	llmList = llms.NewRegistry()
+	llmList.Register(llmstest.New("scripted"))
+	err = run(context.Background(), zap.NewNop(), cfg, llmList, scraper.NewPool(zap.NewNop(), scrapertest.NewBrowser(sitePages), 2), st)
+	if err != nil {
+		t.Fatalf("Error rerunning: %v", err)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"os"
 )
 
-type EscapeRooms []struct {
+type EscapeRoom struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
 }
 
+type EscapeRooms []EscapeRoom
+
 func parseEscapeRooms(filename string) (EscapeRooms, error) {
 	var rooms EscapeRooms
 
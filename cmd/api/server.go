@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+	"go.uber.org/zap"
+)
+
+const jobQueueSize = 100
+
+type jobStatus string
+
+const (
+	jobQueued  jobStatus = "queued"
+	jobRunning jobStatus = "running"
+	jobDone    jobStatus = "done"
+	jobFailed  jobStatus = "failed"
+)
+
+type jobRequest struct {
+	ProviderName string `json:"provider_name"`
+	URL          string `json:"url"`
+	Model        string `json:"model"`
+	Restart      bool   `json:"restart"`
+}
+
+type job struct {
+	ID           string              `json:"id"`
+	ProviderName string              `json:"provider_name"`
+	URL          string              `json:"url"`
+	Model        string              `json:"model"`
+	Status       jobStatus           `json:"status"`
+	Error        string              `json:"error,omitempty"`
+	Information  *output.Information `json:"information,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	StartedAt    *time.Time          `json:"started_at,omitempty"`
+	FinishedAt   *time.Time          `json:"finished_at,omitempty"`
+	rooms        []llms.Room
+}
+
+// storedJob is the persisted form of a job, which in contrast to the API response includes the rooms.
+type storedJob struct {
+	job
+	Rooms []llms.Room `json:"rooms"`
+}
+
+type server struct {
+	ctx     context.Context
+	logger  *zap.Logger
+	cfg     *config.Config
+	llmList *llms.Registry
+	pool    *scraper.Pool
+	store   *store.Store
+
+	mu     sync.RWMutex
+	jobs   map[string]*job
+	queues map[string]chan *job
+}
+
+// serve runs the HTTP API until the process receives SIGINT or SIGTERM.
+// Jobs which were queued or running when the server stopped are resumed.
+func serve(ctx context.Context, logger *zap.Logger, cfg *config.Config, llmList *llms.Registry, pool *scraper.Pool, st *store.Store) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &server{
+		ctx:     ctx,
+		logger:  logger,
+		cfg:     cfg,
+		llmList: llmList,
+		pool:    pool,
+		store:   st,
+		jobs:    make(map[string]*job),
+		queues:  make(map[string]chan *job),
+	}
+
+	err := s.restore()
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              cfg.Serve.Listen,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			logger.Error("failed to shut down http server", zap.Error(err))
+		}
+	}()
+
+	logger.Info("starting http server", zap.String("listen", cfg.Serve.Listen))
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to listen and serve: %w", err)
+	}
+
+	return nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /models", s.handleModels)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("POST /jobs", s.handleCreateJob)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /jobs/{id}/rooms", s.handleGetRooms)
+
+	return mux
+}
+
+func (s *server) handleModels(w http.ResponseWriter, _ *http.Request) {
+	models := []string{}
+	for _, plugin := range s.llmList.Plugins() {
+		models = append(models, plugin.ModelName())
+	}
+	sort.Strings(models)
+
+	writeJSON(w, http.StatusOK, models)
+}
+
+func (s *server) handleListJobs(w http.ResponseWriter, _ *http.Request) {
+	s.mu.RLock()
+	jobs := make([]job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, *j)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request: %w", err))
+
+		return
+	}
+
+	if req.URL == "" || req.Model == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url and model are required"))
+
+		return
+	}
+
+	if s.llmList.Plugin(req.Model) == nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown model: %s", req.Model))
+
+		return
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+
+		return
+	}
+
+	if req.Restart {
+		err = s.store.DeleteCrawlState(req.URL, req.Model)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+
+			return
+		}
+	}
+
+	j := &job{
+		ID:           id,
+		ProviderName: req.ProviderName,
+		URL:          req.URL,
+		Model:        req.Model,
+		Status:       jobQueued,
+		CreatedAt:    time.Now(),
+	}
+
+	err = s.enqueue(j)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": id})
+}
+
+func (s *server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (s *server) handleGetRooms(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+
+		return
+	}
+
+	if j.Status == jobQueued || j.Status == jobRunning {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is %s", j.Status))
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j.rooms)
+}
+
+// job returns a snapshot of the job with the given id.
+func (s *server) job(id string) (job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return job{}, false
+	}
+
+	return *j, true
+}
+
+// enqueue registers the job and hands it to the worker of its model.
+// Every model gets its own worker, because the plugins keep the chat state.
+func (s *server) enqueue(j *job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.queues[j.Model]
+	if !ok {
+		queue = make(chan *job, jobQueueSize)
+		s.queues[j.Model] = queue
+		go s.worker(s.llmList.Plugin(j.Model), queue)
+	}
+
+	select {
+	case queue <- j:
+	default:
+		return fmt.Errorf("job queue of model %s is full", j.Model)
+	}
+	s.jobs[j.ID] = j
+	s.persist(j)
+
+	return nil
+}
+
+// restore loads the stored jobs and queues the ones that did not finish.
+func (s *server) restore() error {
+	var pending []*job
+	err := s.store.Jobs(func(data []byte) error {
+		var sj storedJob
+		err := json.Unmarshal(data, &sj)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal job: %w", err)
+		}
+
+		j := &sj.job
+		j.rooms = sj.Rooms
+		if j.Status == jobQueued || j.Status == jobRunning {
+			pending = append(pending, j)
+
+			return nil
+		}
+
+		s.mu.Lock()
+		s.jobs[j.ID] = j
+		s.mu.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore jobs: %w", err)
+	}
+
+	sort.Slice(pending, func(i, k int) bool {
+		return pending[i].CreatedAt.Before(pending[k].CreatedAt)
+	})
+	for _, j := range pending {
+		j.Status = jobQueued
+		j.StartedAt = nil
+		err = fmt.Errorf("unknown model: %s", j.Model)
+		if s.llmList.Plugin(j.Model) != nil {
+			err = s.enqueue(j)
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.jobs[j.ID] = j
+			s.mu.Unlock()
+			s.finish(j, output.Information{}, nil, err)
+
+			continue
+		}
+		s.logger.Info("resuming job", zap.String("id", j.ID))
+	}
+
+	return nil
+}
+
+// persist stores the job. It must be called while holding the lock.
+func (s *server) persist(j *job) {
+	err := s.store.SaveJob(j.ID, storedJob{job: *j, Rooms: j.rooms})
+	if err != nil {
+		s.logger.Error("failed to persist job", zap.String("id", j.ID), zap.Error(err))
+	}
+}
+
+func (s *server) worker(llm llms.Plugin, queue <-chan *job) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case j := <-queue:
+			started := false
+			err := s.pool.Do(s.ctx, func(page scraper.ScraperPage) error {
+				started = true
+				s.start(j)
+				llm.Guided(false)
+				info, rooms, err := crawlProvider(s.ctx, newAgent(s.logger, s.cfg, s.store, llm, page), 0, EscapeRoom{Name: j.ProviderName, URL: j.URL})
+				if s.ctx.Err() != nil {
+					// the interrupted crawl is resumed with the job after the restart
+					return err
+				}
+				s.finish(j, info, rooms, err)
+
+				return err
+			})
+			// jobs interrupted by the shutdown stay queued and are resumed
+			if err != nil && !started && s.ctx.Err() == nil {
+				s.finish(j, output.Information{}, nil, fmt.Errorf("failed to get a browser page: %w", err))
+			}
+		}
+	}
+}
+
+func (s *server) start(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	j.Status = jobRunning
+	j.StartedAt = &now
+	s.persist(j)
+}
+
+func (s *server) finish(j *job, info output.Information, rooms []llms.Room, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Information = &info
+	j.rooms = rooms
+	j.Status = jobDone
+	if err != nil {
+		j.Status = jobFailed
+		j.Error = err.Error()
+	}
+	s.persist(j)
+
+	s.logger.Info("job finished", zap.String("id", j.ID), zap.String("status", string(j.Status)), zap.Int("rooms", len(rooms)))
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
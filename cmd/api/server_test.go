@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/agent"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/llmstest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper/scrapertest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+	"go.uber.org/zap"
+)
+
+// newTestServer restores the jobs of the store and serves the API of the scripted llm.
+func newTestServer(t *testing.T, st *store.Store, steps ...llmstest.Step) *httptest.Server {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	llmList := llms.NewRegistry()
+	llmList.Register(llmstest.New("scripted", steps...))
+	s := &server{
+		ctx:    ctx,
+		logger: zap.NewNop(),
+		cfg: &config.Config{
+			Limit:          5,
+			ExtractionMode: agent.ExtractionConversation,
+			ContentFormat:  string(scraper.FormatHTML),
+		},
+		llmList: llmList,
+		pool:    scraper.NewPool(zap.NewNop(), scrapertest.NewBrowser(sitePages), 1),
+		store:   st,
+		jobs:    make(map[string]*job),
+		queues:  make(map[string]chan *job),
+	}
+	err := s.restore()
+	if err != nil {
+		t.Fatalf("Error restoring jobs: %v", err)
+	}
+
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+
+	st, err := store.New(filepath.Join(t.TempDir(), "state"))
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+
+	return st
+}
+
+// request sends the request and decodes the JSON response into v, if set. It returns the status code.
+func request(t *testing.T, method, url, body string, v any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil && resp.StatusCode < http.StatusMultipleChoices {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			t.Fatalf("Error decoding response: %v", err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// waitForJob polls the job until it finished.
+func waitForJob(t *testing.T, url, id string) job {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var j job
+		status := request(t, http.MethodGet, url+"/jobs/"+id, "", &j)
+		if status != http.StatusOK {
+			t.Fatalf("Expected status 200 of job %s, got %d", id, status)
+		}
+		if j.Status == jobDone || j.Status == jobFailed {
+			return j
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+
+	return job{}
+}
+
+func TestServerRoutes(t *testing.T) {
+	ts := newTestServer(t, newTestStore(t),
+		llmstest.Step{URLs: []string{gruftURL}, Tokens: 1200},
+		llmstest.Step{Rooms: []llms.Room{gruft}, Tokens: 2400},
+	)
+
+	var models []string
+	if status := request(t, http.MethodGet, ts.URL+"/models", "", &models); status != http.StatusOK || len(models) != 1 || models[0] != "scripted" {
+		t.Errorf("Unexpected models %d %v", status, models)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"invalid json", http.MethodPost, "/jobs", `{`, http.StatusBadRequest},
+		{"missing url", http.MethodPost, "/jobs", `{"model": "scripted"}`, http.StatusBadRequest},
+		{"unknown model", http.MethodPost, "/jobs", `{"url": "` + providerURL + `", "model": "gpt-2"}`, http.StatusBadRequest},
+		{"unknown job", http.MethodGet, "/jobs/unknown", "", http.StatusNotFound},
+		{"rooms of unknown job", http.MethodGet, "/jobs/unknown/rooms", "", http.StatusNotFound},
+		{"unknown path", http.MethodGet, "/providers", "", http.StatusNotFound},
+		{"wrong method", http.MethodDelete, "/jobs", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if status := request(t, tt.method, ts.URL+tt.path, tt.body, nil); status != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, status)
+		}
+	}
+
+	var created map[string]string
+	status := request(t, http.MethodPost, ts.URL+"/jobs", `{"provider_name": "Escape Example", "url": "`+providerURL+`", "model": "scripted"}`, &created)
+	if status != http.StatusAccepted || created["id"] == "" {
+		t.Fatalf("Unexpected created job %d %v", status, created)
+	}
+
+	j := waitForJob(t, ts.URL, created["id"])
+	if j.Status != jobDone || j.Information == nil || j.Information.WebsitesChecked != 2 {
+		t.Errorf("Unexpected job %+v", j)
+	}
+
+	var rooms []llms.Room
+	if status := request(t, http.MethodGet, ts.URL+"/jobs/"+created["id"]+"/rooms", "", &rooms); status != http.StatusOK || len(rooms) != 1 || rooms[0].Name != "Die Gruft" {
+		t.Errorf("Unexpected rooms %d %+v", status, rooms)
+	}
+
+	var jobs []job
+	if status := request(t, http.MethodGet, ts.URL+"/jobs", "", &jobs); status != http.StatusOK || len(jobs) != 1 || jobs[0].ID != created["id"] {
+		t.Errorf("Unexpected jobs %d %+v", status, jobs)
+	}
+}
+
+func TestServerRestoresJobs(t *testing.T) {
+	st := newTestStore(t)
+	created := time.Now().Add(-time.Hour)
+	for _, j := range []storedJob{
+		{job: job{ID: "done", URL: providerURL, Model: "scripted", Status: jobDone, CreatedAt: created}, Rooms: []llms.Room{gruft}},
+		{job: job{ID: "running", URL: providerURL, Model: "scripted", Status: jobRunning, CreatedAt: created}},
+		{job: job{ID: "removed", URL: providerURL, Model: "gpt-2", Status: jobQueued, CreatedAt: created}},
+	} {
+		err := st.SaveJob(j.ID, j)
+		if err != nil {
+			t.Fatalf("Error saving job: %v", err)
+		}
+	}
+
+	ts := newTestServer(t, st,
+		llmstest.Step{Rooms: []llms.Room{gruft}, Tokens: 2400},
+	)
+
+	var rooms []llms.Room
+	if status := request(t, http.MethodGet, ts.URL+"/jobs/done/rooms", "", &rooms); status != http.StatusOK || len(rooms) != 1 {
+		t.Errorf("Expected the rooms of the finished job, got %d %+v", status, rooms)
+	}
+
+	// jobs interrupted by the restart are resumed, jobs of removed models fail
+	if j := waitForJob(t, ts.URL, "running"); j.Status != jobDone {
+		t.Errorf("Expected the interrupted job to be resumed, got %+v", j)
+	}
+	if j := waitForJob(t, ts.URL, "removed"); j.Status != jobFailed || !strings.Contains(j.Error, "unknown model") {
+		t.Errorf("Expected the job of the removed model to fail, got %+v", j)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	config "github.com/martinbockt/esc-llm-webscraper/cmd/api/internal"
+	"github.com/martinbockt/esc-llm-webscraper/internal/agent"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+// traceTextLength is the length prompts and tool arguments are shortened to in the timeline.
+const traceTextLength = 200
+
+// viewTrace prints the events of a trace file as a timeline.
+func viewTrace(w io.Writer, cfg *config.TraceViewCmd) error {
+	events, err := agent.ReadTrace(cfg.File)
+	if err != nil {
+		return err
+	}
+
+	textLength := traceTextLength
+	if cfg.Full {
+		textLength = -1
+	}
+
+	return printTimeline(w, events, textLength)
+}
+
+func printTimeline(w io.Writer, events []agent.Event, textLength int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var start time.Time
+	for _, event := range events {
+		if event.Type == agent.EventStart {
+			start = event.Time
+		}
+		fmt.Fprintf(tw, "+%s\tstep %d\t%s\t%s\n", event.Time.Sub(start).Round(time.Millisecond), event.Step, event.Type, eventSummary(event))
+
+		for _, turn := range event.Sent {
+			fmt.Fprintf(tw, "\t\t\t  > %s\n", turnSummary(turn, textLength))
+		}
+		if event.Received != nil {
+			fmt.Fprintf(tw, "\t\t\t  < %s\n", turnSummary(*event.Received, textLength))
+			for _, call := range event.Received.ToolCalls {
+				fmt.Fprintf(tw, "\t\t\t    %s %s\n", call.Name, shorten(call.Arguments, textLength))
+			}
+		}
+		if event.Err != "" {
+			fmt.Fprintf(tw, "\t\t\t  error: %s\n", event.Err)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func eventSummary(event agent.Event) string {
+	parts := []string{}
+	switch event.Type {
+	case agent.EventStart:
+		parts = append(parts, fmt.Sprintf("%s %s with %s", event.Provider, event.URL, event.Model))
+	case agent.EventNavigate, agent.EventSkip:
+		parts = append(parts, event.URL)
+		if event.ContentLength > 0 {
+			parts = append(parts, fmt.Sprintf("%d chars", event.ContentLength))
+		}
+		if event.Screenshots > 0 {
+			parts = append(parts, fmt.Sprintf("%d screenshots", event.Screenshots))
+		}
+		if event.Consent != "" {
+			parts = append(parts, fmt.Sprintf("%s consent banner", event.Consent))
+		}
+	case agent.EventChunk:
+		parts = append(parts, fmt.Sprintf("%s part %d of %d", event.URL, event.Chunk, event.Chunks))
+	}
+	if event.Tokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", event.Tokens))
+	}
+	if event.Duration > 0 {
+		parts = append(parts, event.Duration.Round(time.Millisecond).String())
+	}
+	if len(event.URLs) > 0 {
+		parts = append(parts, fmt.Sprintf("urls %s", strings.Join(event.URLs, ", ")))
+	}
+	if event.Rooms > 0 || event.Type == agent.EventDone {
+		parts = append(parts, fmt.Sprintf("%d rooms", event.Rooms))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func turnSummary(turn llms.Turn, textLength int) string {
+	role := string(turn.Role)
+	if turn.ToolName != "" {
+		role += " " + turn.ToolName
+	}
+	if turn.Text == "" {
+		return role
+	}
+
+	return role + ": " + shorten(turn.Text, textLength)
+}
+
+// shorten collapses the whitespace of the text and cuts it to the length, a negative length keeps the text.
+func shorten(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if length < 0 || len(text) <= length {
+		return text
+	}
+
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	return string(runes[:length]) + "…"
+}
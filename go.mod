@@ -12,6 +12,7 @@ require (
 	github.com/tmc/langchaingo v0.1.12
 	go.uber.org/zap v1.27.0
 	golang.org/x/net v0.28.0
+	google.golang.org/api v0.196.0
 )
 
 require (
@@ -52,7 +53,6 @@ require (
 	golang.org/x/sys v0.24.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 	golang.org/x/time v0.6.0 // indirect
-	google.golang.org/api v0.196.0 // indirect
 	google.golang.org/genproto v0.0.0-20240903143218-8af14fe29dc1 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20240903143218-8af14fe29dc1 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240903143218-8af14fe29dc1 // indirect
@@ -0,0 +1,627 @@
+// Package agent lets an llm navigate the website of an escape room provider until it lists the rooms.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/schema"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	taskPrompt = "List all escape rooms of the website. If there is, use the Escape Room detail pages as content source."
+	// tokenReserve is kept free of the context window for the system prompt, the tool schemas and the answer.
+	tokenReserve = 10000
+	// DefaultLimit is the default maximum number of llm requests of a run.
+	DefaultLimit = 50
+)
+
+const (
+	// ExtractionConversation sends whole pages to the llm and cuts them if they exceed the context window.
+	ExtractionConversation = "conversation"
+	// ExtractionChunked extracts the rooms of pages exceeding half of the context window chunk by chunk.
+	ExtractionChunked = "chunked"
+)
+
+const (
+	// VisionOff sends the content of the pages only.
+	VisionOff = "off"
+	// VisionAlongside sends full-page screenshots alongside the content of the pages to llms with image support.
+	VisionAlongside = "alongside"
+	// VisionInstead sends full-page screenshots instead of the content of the pages to llms with image support.
+	VisionInstead = "instead"
+)
+
+const (
+	screenshotPrompt = "Screenshots of the pages above, every page from top to bottom."
+	// screenshotContent replaces the content of a page sent as screenshots.
+	screenshotContent = "see the attached screenshots."
+)
+
+var pageURLPattern = regexp.MustCompile(`Current URL: (\S+?); Current website content:`)
+
+// Provider is the escape room provider whose website is crawled.
+type Provider struct {
+	Name string
+	URL  string
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	ProviderName string
+	ProviderURL  string
+	Model        string
+	Rooms        []llms.Room
+
+	Steps                int
+	LLMDuration          time.Duration
+	RequestDuration      time.Duration
+	TokenCount           int
+	TokenLimitReached    bool
+	ContextCompactions   int
+	ChunksExtracted      int
+	ConsentBanners       int
+	InvalidURLs          int
+	ToolRetries          int
+	WebsitesChecked      int
+	WebsiteMaxLength     int
+	WebsiteReducedLength int
+	ContentFormat        string
+	VisionMode           string
+
+	// Events are the events of this run, they are not restored for resumed runs.
+	Events []Event
+}
+
+// Agent crawls providers with an llm and a browser page.
+type Agent struct {
+	llm            llms.Plugin
+	page           scraper.ScraperPage
+	logger         *zap.Logger
+	store          *store.Store
+	limit          int
+	extractionMode string
+	contentFormat  scraper.Format
+	onEvent        func(Event)
+	traceDir       string
+	allowedDomains []string
+	visionMode     string
+}
+
+// Option configures the agent.
+type Option func(*Agent)
+
+// WithLogger sets the logger of the agent.
+func WithLogger(logger *zap.Logger) Option {
+	return func(a *Agent) {
+		a.logger = logger
+	}
+}
+
+// WithStore checkpoints the progress of every run to the store, so an interrupted
+// run replays its conversation and continues where it stopped.
+func WithStore(st *store.Store) Option {
+	return func(a *Agent) {
+		a.store = st
+	}
+}
+
+// WithLimit sets the maximum number of llm requests of a run.
+func WithLimit(limit int) Option {
+	return func(a *Agent) {
+		a.limit = limit
+	}
+}
+
+// WithExtractionMode sets how pages exceeding the context window are handled, ExtractionConversation or ExtractionChunked.
+func WithExtractionMode(mode string) Option {
+	return func(a *Agent) {
+		a.extractionMode = mode
+	}
+}
+
+// WithContentFormat sets the format of the page content, used to split oversized pages.
+func WithContentFormat(format scraper.Format) Option {
+	return func(a *Agent) {
+		a.contentFormat = format
+	}
+}
+
+// WithEventHandler calls the handler for every event of a run.
+func WithEventHandler(handler func(Event)) Option {
+	return func(a *Agent) {
+		a.onEvent = handler
+	}
+}
+
+// WithTraceDir writes the events of every run as JSON lines to a trace file in the directory, see TracePath.
+func WithTraceDir(dir string) Option {
+	return func(a *Agent) {
+		a.traceDir = dir
+	}
+}
+
+// WithAllowedDomains sets the domains outside of the website of the provider the llm may navigate to, BookingPlatforms by default.
+func WithAllowedDomains(domains []string) Option {
+	return func(a *Agent) {
+		a.allowedDomains = domains
+	}
+}
+
+// WithVisionMode sets whether screenshots of the pages are sent to llms with image support, VisionOff by default.
+func WithVisionMode(mode string) Option {
+	return func(a *Agent) {
+		a.visionMode = mode
+	}
+}
+
+// New creates an agent letting the llm navigate the page.
+func New(llm llms.Plugin, page scraper.ScraperPage, opts ...Option) *Agent {
+	a := &Agent{
+		llm:            llm,
+		page:           page,
+		logger:         zap.NewNop(),
+		limit:          DefaultLimit,
+		extractionMode: ExtractionConversation,
+		contentFormat:  scraper.FormatHTML,
+		allowedDomains: BookingPlatforms,
+		visionMode:     VisionOff,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// run holds the state of a single run.
+type run struct {
+	*Agent
+	state    *store.CrawlState
+	frontier *Frontier
+	events   []Event
+	trace    *traceWriter
+}
+
+// Run lets the llm navigate the website of the provider until it lists the rooms,
+// fails or the limit of llm requests is reached. The result is returned on errors too.
+// A run whose context is cancelled stops without finishing, so it is resumed from the store.
+func (a *Agent) Run(ctx context.Context, provider Provider) (*Result, error) {
+	llm := a.llm
+	logger := a.logger
+
+	var state *store.CrawlState
+	var err error
+	if a.store != nil {
+		state, err = a.store.CrawlState(provider.URL, llm.ModelName())
+		if err != nil {
+			return nil, err
+		}
+	}
+	visionMode := a.visionMode
+	if visionMode != VisionOff && !llm.ImageSupport() {
+		logger.Warn("llm has no image support, sending page content only", zap.String("model", llm.ModelName()))
+		visionMode = VisionOff
+	}
+	// the state of a crawl with other settings would mix their results
+	if state != nil && (state.ContentFormat != string(a.contentFormat) || state.ExtractionMode != a.extractionMode || state.VisionMode != visionMode) {
+		logger.Info("discarding crawl state of other settings", zap.String("provider", provider.Name), zap.String("model", llm.ModelName()),
+			zap.String("content format", state.ContentFormat), zap.String("extraction mode", state.ExtractionMode), zap.String("vision mode", state.VisionMode))
+		state = nil
+	}
+	if state == nil {
+		state = store.NewCrawlState(provider.Name, provider.URL, llm.ModelName())
+		state.ContentFormat = string(a.contentFormat)
+		state.ExtractionMode = a.extractionMode
+		state.VisionMode = visionMode
+	}
+	vision := state.VisionMode == VisionAlongside || state.VisionMode == VisionInstead
+
+	r := &run{
+		Agent: a,
+		state: state,
+	}
+	if state.Done {
+		logger.Info("provider already crawled", zap.String("provider", provider.Name), zap.String("model", llm.ModelName()))
+
+		return r.result(), stateError(state)
+	}
+
+	r.frontier, err = NewFrontier(provider.URL, a.allowedDomains)
+	if err != nil {
+		return nil, err
+	}
+	for _, visited := range state.Visited {
+		r.frontier.Visit(visited)
+	}
+
+	if a.traceDir != "" {
+		r.trace, err = openTrace(TracePath(a.traceDir, llm.ModelName(), provider.URL))
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			err := r.trace.Close()
+			if err != nil {
+				logger.Error("failed to close trace", zap.Error(err))
+			}
+		}()
+	}
+	r.emit(Event{Type: EventStart, Model: llm.ModelName(), Provider: provider.Name, URL: provider.URL})
+
+	logger.Info("loaded llm", zap.String("name", llm.ModelName()), zap.Int("resumed steps", state.Steps))
+	conversation := state.Conversation
+	budget := llm.ContextWindow() - tokenReserve
+	chunkBudget := budget / 2
+	response := []llms.LlmResposeWithChatID{
+		{
+			UrlsResp: llms.UrlsResp{URLs: state.Pending},
+			ChatID:   "",
+		},
+	}
+	// a resumed conversation either waits for the llm or for the results of its last tool calls
+	awaitingLLM := false
+	if last := conversation.Last(); last != nil {
+		awaitingLLM = last.Role != llms.RoleAssistant
+		if !awaitingLLM {
+			response = responsesFromTurn(*last)
+		}
+	}
+	var done bool
+	startTime := time.Now().Add(-state.RequestDuration)
+
+	finish := func(err error) (*Result, error) {
+		state.Rooms = llms.MergeRooms(state.Rooms)
+		state.Done = true
+		state.RequestDuration = time.Since(startTime)
+		if err != nil {
+			state.Error = err.Error()
+		}
+		r.checkpoint()
+		r.emit(Event{Type: EventDone, Rooms: len(state.Rooms), Err: errorString(err)})
+
+		return r.result(), err
+	}
+
+	for i := state.Steps; i < a.limit; i++ {
+		if !awaitingLLM {
+			done = true
+			images := [][]byte{}
+			// relative URLs of the llm refer to the page it saw last
+			base := r.lastPageURL()
+			for _, resp := range response {
+				var websiteMaxLength, shortLength int
+				prompt := ""
+				if resp.Err != nil {
+					// the llm gets the chance to correct its call
+					done = false
+					state.ToolRetries++
+					logger.Warn("failed tool call", zap.Error(resp.Err))
+					r.emit(Event{Type: EventToolError, Err: resp.Err.Error()})
+					addPrompt(conversation, toolErrorBlock(resp.Err), resp)
+
+					continue
+				}
+				if len(resp.URLs) != 0 {
+					done = false
+				} else {
+					addPrompt(conversation, "added", resp)
+
+					continue
+				}
+
+				for _, requested := range resp.URLs {
+					url := Resolve(base, requested)
+					canonical, checkErr := r.frontier.Check(url)
+					if checkErr != nil {
+						logger.Info("skipped url", zap.String("url", url), zap.Error(checkErr))
+						r.emit(Event{Type: EventSkip, URL: url, Err: checkErr.Error()})
+						prompt += skippedBlock(url, checkErr)
+
+						continue
+					}
+
+					var content string
+					err = a.page.Navigate(ctx, url)
+					if errors.Is(err, scraper.ErrDisallowed) {
+						logger.Info("skipped url", zap.String("url", url), zap.Error(err))
+						r.emit(Event{Type: EventSkip, URL: url, Err: err.Error()})
+						prompt += skippedBlock(url, err)
+						err = nil
+
+						continue
+					}
+					if err != nil {
+						err = fmt.Errorf("failed to navigate: %w", err)
+						r.emit(Event{Type: EventNavigate, URL: url, Err: err.Error()})
+
+						break
+					}
+
+					content, websiteMaxLength, shortLength, err = a.page.PageContent()
+					state.WebsiteMaxLength += websiteMaxLength
+					state.WebsiteReducedLength += shortLength
+					if err != nil {
+						err = fmt.Errorf("failed to get page content: %w", err)
+						r.emit(Event{Type: EventNavigate, URL: url, Err: err.Error()})
+
+						break
+					}
+					metadata := a.page.Metadata()
+					if metadata.Consent != "" {
+						state.ConsentBanners++
+					}
+					screenshots := 0
+					if vision {
+						tiles, screenshotErr := a.page.GetScreenshotTiles()
+						if screenshotErr != nil {
+							logger.Warn("failed to take screenshots, sending page content only", zap.String("url", url), zap.Error(screenshotErr))
+						}
+						images = append(images, tiles...)
+						screenshots = len(tiles)
+					}
+					r.emit(Event{Type: EventNavigate, URL: url, ContentLength: shortLength, Tokens: llms.EstimateTokens(content), Consent: metadata.Consent, Screenshots: screenshots})
+
+					logger.Info("page content length", zap.Int("initial length", state.WebsiteMaxLength), zap.Int("shortened length", state.WebsiteReducedLength))
+					state.Pages = append(state.Pages, store.Page{URL: url, Content: content})
+					if screenshots > 0 && state.VisionMode == VisionInstead {
+						content = screenshotContent
+					}
+					if a.extractionMode == ExtractionChunked && llms.EstimateTokens(content) > chunkBudget {
+						pageRooms, extractErr := r.extractChunked(ctx, url, content, chunkBudget)
+						r.addRooms(url, pageRooms)
+						if extractErr != nil {
+							logger.Error("failed to extract chunks", zap.String("url", url), zap.Error(extractErr))
+						}
+						content = chunkedSummary(pageRooms)
+					}
+					prompt += pageBlock(url, content)
+					state.WebsitesChecked++
+					state.MarkVisited(requested, canonical)
+					r.frontier.Visit(canonical)
+				}
+				addPrompt(conversation, prompt, resp)
+			}
+			attachScreenshots(conversation, images)
+			if ctx.Err() != nil {
+				return r.interrupt(ctx.Err())
+			}
+			r.checkpoint()
+			if done || err != nil || len(response) == 0 {
+				logger.Info("done", zap.Bool("done", done), zap.Error(err))
+				llm.ResetChat()
+
+				return finish(err)
+			}
+		}
+		awaitingLLM = false
+
+		if conversation.FitTokenBudget(budget, compactPages) {
+			state.ContextCompactions++
+			logger.Info("compacted conversation", zap.Int("budget", budget), zap.Int("estimated tokens", conversation.EstimateTokens()))
+			r.emit(Event{Type: EventCompaction, Tokens: conversation.EstimateTokens()})
+		}
+
+		sent := conversation.Len()
+		result, duration, reqTokenCount, err := llm.ExecutePrompt(ctx, conversation)
+		if err != nil && ctx.Err() != nil {
+			return r.interrupt(ctx.Err())
+		}
+		state.Steps++
+		state.LLMDuration += duration
+		state.TokenCount += reqTokenCount
+		if err != nil {
+			logger.Error("failed to prompt", zap.Error(err))
+		}
+		logger.Info("result", zap.Any("result", result))
+		response = result
+		state.Pending = nil
+		event := Event{Type: EventPrompt, Duration: duration, Tokens: reqTokenCount, Err: errorString(err)}
+		event.Sent, event.Received = exchange(conversation, sent)
+		for _, resp := range result {
+			r.addRooms(r.lastPageURL(), resp.Rooms)
+			state.Pending = append(state.Pending, resp.URLs...)
+			event.URLs = append(event.URLs, resp.URLs...)
+			event.Rooms += len(resp.Rooms)
+		}
+		r.emit(event)
+		r.checkpoint()
+		if err != nil || i == a.limit-1 {
+			llm.ResetChat()
+			if len(state.Rooms) == 0 {
+				state.TokenLimitReached = true
+				r.emit(Event{Type: EventFallback})
+				// we assume error means token limit reached
+				for i, page := range state.Pages {
+					if i == 0 {
+						continue // skip the first one / main page
+					}
+
+					pageRooms, err := r.extractChunked(ctx, page.URL, page.Content, chunkBudget)
+					r.addRooms(page.URL, pageRooms)
+					if err != nil {
+						logger.Error("failed to execute prompt:", zap.Error(err))
+
+						break
+					}
+				}
+			}
+			logger.Error("failed to execute prompt", zap.Error(err), zap.Int("limit", i))
+
+			return finish(err)
+		}
+	}
+
+	return finish(err)
+}
+
+// interrupt stops a run whose context was cancelled. The state of the current step isn't checkpointed
+// and the run isn't finished, so the step is repeated when the run is resumed.
+func (r *run) interrupt(err error) (*Result, error) {
+	r.llm.ResetChat()
+	r.logger.Info("crawl interrupted", zap.String("provider", r.state.ProviderName), zap.Error(err))
+
+	return r.result(), fmt.Errorf("crawl interrupted: %w", err)
+}
+
+func (r *run) checkpoint() {
+	if r.store == nil {
+		return
+	}
+
+	err := r.store.SaveCrawlState(r.state)
+	if err != nil {
+		r.logger.Error("failed to checkpoint crawl", zap.Error(err))
+	}
+}
+
+func (r *run) emit(event Event) {
+	event.Step = r.state.Steps
+	event.Time = time.Now()
+	r.events = append(r.events, event)
+	if r.trace != nil {
+		r.trace.write(event)
+	}
+	if r.onEvent != nil {
+		r.onEvent(event)
+	}
+}
+
+func (r *run) result() *Result {
+	state := r.state
+
+	return &Result{
+		ProviderName:         state.ProviderName,
+		ProviderURL:          state.ProviderURL,
+		Model:                state.Model,
+		Rooms:                state.Rooms,
+		Steps:                state.Steps,
+		LLMDuration:          state.LLMDuration,
+		RequestDuration:      state.RequestDuration,
+		TokenCount:           state.TokenCount,
+		TokenLimitReached:    state.TokenLimitReached,
+		ContextCompactions:   state.ContextCompactions,
+		ChunksExtracted:      state.ChunksExtracted,
+		ConsentBanners:       state.ConsentBanners,
+		InvalidURLs:          state.InvalidURLs,
+		ToolRetries:          state.ToolRetries,
+		WebsitesChecked:      state.WebsitesChecked,
+		WebsiteMaxLength:     state.WebsiteMaxLength,
+		WebsiteReducedLength: state.WebsiteReducedLength,
+		ContentFormat:        state.ContentFormat,
+		VisionMode:           state.VisionMode,
+		Events:               r.events,
+	}
+}
+
+// addPrompt answers the tool call of the response or, if there is none, adds a user turn with the task.
+func addPrompt(conversation *llms.Conversation, text string, resp llms.LlmResposeWithChatID) {
+	if resp.ChatID == "" && resp.ToolName == "" {
+		conversation.AddUser(taskPrompt + text)
+
+		return
+	}
+
+	conversation.AddToolResult(resp.ChatID, resp.ToolName, text)
+}
+
+// attachScreenshots adds the screenshots of the pages of a step to the user turn with the task or,
+// after tool results, as a user turn of its own, because most llms only accept images from the user.
+func attachScreenshots(conversation *llms.Conversation, images [][]byte) {
+	if len(images) == 0 {
+		return
+	}
+
+	if last := conversation.Last(); last != nil && last.Role == llms.RoleUser {
+		last.Images = append(last.Images, images...)
+
+		return
+	}
+	conversation.AddUser(screenshotPrompt, images...)
+}
+
+func pageBlock(url, content string) string {
+	return fmt.Sprintf("Current URL: %s; Current website content: %s", url, content)
+}
+
+// toolErrorBlock tells the llm why its tool call failed, so it can correct it.
+func toolErrorBlock(err error) string {
+	if errors.Is(err, llms.ErrUnknownTool) {
+		return fmt.Sprintf("%s. Only call the tools %s and %s.", err, llms.URLsName, llms.RoomsName)
+	}
+	if errors.Is(err, schema.ErrItemDropped) {
+		return fmt.Sprintf("%s. The other rooms were added. Call the tool again with only the dropped rooms, correcting the invalid arguments to match its schema.", err)
+	}
+
+	return fmt.Sprintf("%s. Call the tool again with all arguments, correcting the invalid ones to match its schema.", err)
+}
+
+// skippedBlock tells the llm why the page of the URL was not loaded.
+func skippedBlock(url string, err error) string {
+	switch {
+	case errors.Is(err, ErrVisited):
+		return fmt.Sprintf("You already have the page %s, its content was sent earlier in this conversation. ", url)
+	case errors.Is(err, ErrOffsite):
+		return fmt.Sprintf("The page %s was not loaded, it is not part of the website of the escape room provider. ", url)
+	case errors.Is(err, scraper.ErrDisallowed):
+		return fmt.Sprintf("The page %s was not loaded, the robots.txt of the website disallows it. ", url)
+	default:
+		return fmt.Sprintf("The page %s was not loaded, it is no valid absolute URL. ", url)
+	}
+}
+
+// compactPages replaces the page contents of a turn by a note which pages were dropped.
+// The text in front of the first page, e.g. the task, is kept.
+func compactPages(turn llms.Turn) string {
+	matches := pageURLPattern.FindAllStringSubmatchIndex(turn.Text, -1)
+	if len(matches) == 0 {
+		return turn.Text
+	}
+
+	urls := make([]string, 0, len(matches))
+	for _, match := range matches {
+		urls = append(urls, turn.Text[match[2]:match[3]])
+	}
+
+	return turn.Text[:matches[0][0]] + fmt.Sprintf("The content of %s was removed to stay within the context window.", strings.Join(urls, ", "))
+}
+
+// responsesFromTurn restores the responses of an assistant turn of a resumed conversation.
+func responsesFromTurn(turn llms.Turn) []llms.LlmResposeWithChatID {
+	if len(turn.ToolCalls) == 0 {
+		return []llms.LlmResposeWithChatID{{}}
+	}
+
+	responses := []llms.LlmResposeWithChatID{}
+	for _, toolCall := range turn.ToolCalls {
+		resp, err := llms.Tools.Decode(toolCall)
+		resp.Err = err
+		responses = append(responses, resp)
+	}
+
+	return responses
+}
+
+func stateError(state *store.CrawlState) error {
+	if state.Error == "" {
+		return nil
+	}
+
+	return errors.New(state.Error)
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
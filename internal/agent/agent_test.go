@@ -0,0 +1,475 @@
+package agent_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/agent"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/llmstest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper/scrapertest"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+)
+
+const (
+	providerURL = "https://escape.example.com/"
+	gruftURL    = "https://escape.example.com/rooms/gruft/"
+	laborURL    = "https://escape.example.com/rooms/labor/"
+)
+
+var sitePages = map[string]string{
+	providerURL: `<html><body><h1>Escape Rooms</h1><a href="/rooms/gruft/">Die Gruft</a><a href="/rooms/labor/">Das Labor</a></body></html>`,
+	gruftURL:    `<html><body><h1>Die Gruft</h1><p>Horror, 2-6 Spieler, 60 Minuten</p><a href="/buchen">Buchen</a></body></html>`,
+	laborURL:    `<html><body><h1>Das Labor</h1><p>Science Fiction, 2-4 Spieler, 90 Minuten</p></body></html>`,
+}
+
+var (
+	provider = agent.Provider{Name: "Escape Example", URL: providerURL}
+
+	gruft = llms.Room{Name: "Die Gruft", PlayersMin: 2, PlayersMax: 6, Duration: 60, Genre: "Horror", DetailPageURL: gruftURL, BookingURL: "/buchen"}
+	labor = llms.Room{Name: "Das Labor", PlayersMin: 2, PlayersMax: 4, Duration: 90, Genre: "Science Fiction", DetailPageURL: laborURL}
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name              string
+		limit             int
+		steps             []llmstest.Step
+		wantRooms         []string
+		wantNavigations   []string
+		wantErr           bool
+		wantTokenLimit    bool
+		wantTokens        int
+		wantWebsites      int
+		wantRoomToolsOnly int
+	}{
+		{
+			name:  "follows urls",
+			limit: 5,
+			steps: []llmstest.Step{
+				{URLs: []string{gruftURL, laborURL}},
+				{Rooms: []llms.Room{gruft, labor}},
+			},
+			wantRooms:       []string{"Die Gruft", "Das Labor"},
+			wantNavigations: []string{providerURL, gruftURL, laborURL},
+			wantWebsites:    3,
+		},
+		{
+			name:  "answer without tool call",
+			limit: 5,
+			steps: []llmstest.Step{
+				{Text: "There are no escape rooms."},
+			},
+			wantNavigations: []string{providerURL},
+			wantWebsites:    1,
+		},
+		{
+			name:  "limit reached",
+			limit: 2,
+			steps: []llmstest.Step{
+				{URLs: []string{gruftURL}},
+				{URLs: []string{laborURL}},
+				// the fallback extracts the visited pages except the start page
+				{Rooms: []llms.Room{gruft}},
+			},
+			wantRooms:         []string{"Die Gruft"},
+			wantNavigations:   []string{providerURL, gruftURL},
+			wantTokenLimit:    true,
+			wantWebsites:      2,
+			wantRoomToolsOnly: 1,
+		},
+		{
+			name:  "token limit fallback",
+			limit: 5,
+			steps: []llmstest.Step{
+				{URLs: []string{gruftURL, laborURL}},
+				{Err: errors.New("maximum context length exceeded")},
+				{Rooms: []llms.Room{gruft}},
+				{Rooms: []llms.Room{labor}},
+			},
+			wantRooms:         []string{"Die Gruft", "Das Labor"},
+			wantNavigations:   []string{providerURL, gruftURL, laborURL},
+			wantErr:           true,
+			wantTokenLimit:    true,
+			wantWebsites:      3,
+			wantRoomToolsOnly: 2,
+		},
+		{
+			name:  "fallback corrects dropped rooms",
+			limit: 5,
+			steps: []llmstest.Step{
+				{URLs: []string{gruftURL, laborURL}, Tokens: 1200},
+				{Err: errors.New("maximum context length exceeded")},
+				// the valid room is kept, the chunk is prompted again for the dropped one
+				{Call: &llms.ToolCall{Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Horror"},{"name":"Die Gruft 2","genre":"Thriller"}]}`}, Tokens: 800},
+				{Rooms: []llms.Room{}, Tokens: 600},
+				{Rooms: []llms.Room{labor}, Tokens: 700},
+			},
+			wantRooms:         []string{"Die Gruft", "Das Labor"},
+			wantNavigations:   []string{providerURL, gruftURL, laborURL},
+			wantErr:           true,
+			wantTokenLimit:    true,
+			wantWebsites:      3,
+			wantRoomToolsOnly: 3,
+			wantTokens:        3300,
+		},
+		{
+			name:  "navigation error",
+			limit: 5,
+			steps: []llmstest.Step{
+				{URLs: []string{"https://escape.example.com/missing/"}},
+			},
+			wantNavigations: []string{providerURL, "https://escape.example.com/missing/"},
+			wantErr:         true,
+			wantWebsites:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := llmstest.New("scripted", tt.steps...)
+			browser := scrapertest.NewBrowser(sitePages)
+			page, err := browser.CreatePage()
+			if err != nil {
+				t.Fatalf("Error creating page: %v", err)
+			}
+
+			result, err := newTestAgent(t, llm, page, tt.limit).Run(context.Background(), provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %t, got %v", tt.wantErr, err)
+			}
+
+			names := []string{}
+			for _, room := range result.Rooms {
+				names = append(names, room.Name)
+			}
+			if !slices.Equal(names, tt.wantRooms) {
+				t.Errorf("Expected rooms %v, got %v", tt.wantRooms, names)
+			}
+			if got := browser.Navigations(); !slices.Equal(got, tt.wantNavigations) {
+				t.Errorf("Expected navigations %v, got %v", tt.wantNavigations, got)
+			}
+			if result.TokenLimitReached != tt.wantTokenLimit {
+				t.Errorf("Expected token limit reached %t", tt.wantTokenLimit)
+			}
+			if result.TokenCount != tt.wantTokens {
+				t.Errorf("Expected the tokens of all prompts %d, got %d", tt.wantTokens, result.TokenCount)
+			}
+			if result.WebsitesChecked != tt.wantWebsites {
+				t.Errorf("Expected %d websites checked, got %d", tt.wantWebsites, result.WebsitesChecked)
+			}
+			if got := llm.RoomToolOnlyPrompts(); got != tt.wantRoomToolsOnly {
+				t.Errorf("Expected %d room tool only prompts, got %d", tt.wantRoomToolsOnly, got)
+			}
+			if llm.Steps() != len(tt.steps) {
+				t.Errorf("Expected all %d steps to be used, got %d", len(tt.steps), llm.Steps())
+			}
+		})
+	}
+}
+
+func TestRunResolvesRoomURLs(t *testing.T) {
+	mailed := labor
+	mailed.BookingURL = "mailto:labor@escape.example.com"
+	mailed.ImageURL = "labor.jpg"
+	llm := llmstest.New("scripted",
+		llmstest.Step{URLs: []string{laborURL}},
+		llmstest.Step{Rooms: []llms.Room{gruft, mailed}},
+	)
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	result, err := newTestAgent(t, llm, page, 5).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error crawling provider: %v", err)
+	}
+	if len(result.Rooms) != 2 {
+		t.Fatalf("Expected both rooms, got %+v", result.Rooms)
+	}
+	if result.Rooms[0].BookingURL != "https://escape.example.com/buchen" {
+		t.Errorf("Expected an absolute booking URL, got %q", result.Rooms[0].BookingURL)
+	}
+	// the room with a mail link as booking URL is kept without it
+	if result.Rooms[1].BookingURL != "" {
+		t.Errorf("Expected the mail link to be removed, got %q", result.Rooms[1].BookingURL)
+	}
+	// relative URLs are resolved against the page the rooms were found on
+	if result.Rooms[1].ImageURL != laborURL+"labor.jpg" {
+		t.Errorf("Expected the image URL on the page of the room, got %q", result.Rooms[1].ImageURL)
+	}
+	if result.InvalidURLs != 3 {
+		t.Errorf("Expected 3 invalid URLs, got %d", result.InvalidURLs)
+	}
+}
+
+func TestRunSkipsURLs(t *testing.T) {
+	llm := llmstest.New("scripted",
+		// the relative URL is resolved against the provider page
+		llmstest.Step{URLs: []string{"https://ESCAPE.example.com/#rooms", "https://www.tripadvisor.com/escape", "rooms/gruft/", laborURL}},
+		llmstest.Step{Rooms: []llms.Room{gruft}},
+	)
+	browser := scrapertest.NewBrowser(sitePages)
+	browser.Disallowed = []string{laborURL}
+	page, _ := browser.CreatePage()
+
+	result, err := newTestAgent(t, llm, page, 5).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+	if want := []string{providerURL, gruftURL, laborURL}; !slices.Equal(browser.Navigations(), want) {
+		t.Errorf("Expected navigations %v, got %v", want, browser.Navigations())
+	}
+	if result.WebsitesChecked != 2 {
+		t.Errorf("Expected 2 websites checked, got %d", result.WebsitesChecked)
+	}
+
+	toolResult := llm.Conversations()[1].Last().Text
+	for _, want := range []string{"You already have the page https://ESCAPE.example.com/#rooms", "https://www.tripadvisor.com/escape was not loaded", "Current URL: " + gruftURL, laborURL + " was not loaded, the robots.txt"} {
+		if !strings.Contains(toolResult, want) {
+			t.Errorf("Expected the tool result to contain %q, got %q", want, toolResult)
+		}
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	llm := llmstest.New("scripted",
+		llmstest.Step{URLs: []string{gruftURL}, Tokens: 1200},
+		llmstest.Step{Rooms: []llms.Room{gruft}, Tokens: 2400},
+	)
+	browser := scrapertest.NewBrowser(sitePages)
+	browser.Consent = map[string]string{gruftURL: "borlabs"}
+	page, _ := browser.CreatePage()
+
+	handled := []agent.Event{}
+	a := agent.New(llm, page, agent.WithEventHandler(func(event agent.Event) {
+		handled = append(handled, event)
+	}))
+	result, err := a.Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+
+	types := []agent.EventType{}
+	for _, event := range result.Events {
+		types = append(types, event.Type)
+	}
+	want := []agent.EventType{agent.EventStart, agent.EventNavigate, agent.EventPrompt, agent.EventNavigate, agent.EventPrompt, agent.EventDone}
+	if !slices.Equal(types, want) {
+		t.Errorf("Expected events %v, got %v", want, types)
+	}
+	if len(handled) != len(result.Events) {
+		t.Errorf("Expected %d handled events, got %d", len(result.Events), len(handled))
+	}
+	if event := result.Events[2]; event.Step != 1 || event.Tokens != 1200 || !slices.Equal(event.URLs, []string{gruftURL}) {
+		t.Errorf("Unexpected prompt event %+v", event)
+	}
+	if event := result.Events[3]; event.URL != gruftURL || event.Consent != "borlabs" || result.ConsentBanners != 1 {
+		t.Errorf("Expected the consent banner of %s to be reported, got %+v and %d banners", gruftURL, event, result.ConsentBanners)
+	}
+}
+
+func TestRunVision(t *testing.T) {
+	for _, images := range []bool{true, false} {
+		llm := llmstest.New("scripted",
+			llmstest.Step{URLs: []string{gruftURL}},
+			llmstest.Step{Rooms: []llms.Room{gruft}},
+		)
+		llm.Images = images
+		page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+		result, err := agent.New(llm, page, agent.WithVisionMode(agent.VisionInstead)).Run(context.Background(), provider)
+		if err != nil {
+			t.Fatalf("Error running agent: %v", err)
+		}
+
+		task := llm.Conversations()[0].Turns[0]
+		last := llm.Conversations()[1].Last()
+		if !images {
+			// llms without image support get the page content
+			if result.VisionMode != agent.VisionOff || len(task.Images) != 0 || last.Role != llms.RoleTool {
+				t.Errorf("Expected no screenshots without image support, got mode %q and %d images", result.VisionMode, len(task.Images))
+			}
+
+			continue
+		}
+
+		if len(task.Images) != 1 || strings.Contains(task.Text, "<h1>") {
+			t.Errorf("Expected the start page as screenshot instead of content, got %d images and %q", len(task.Images), task.Text)
+		}
+		// screenshots of tool results follow as user turn
+		if last.Role != llms.RoleUser || len(last.Images) != 1 || string(last.Images[0]) != "screenshot of "+gruftURL {
+			t.Errorf("Expected the screenshot of %s after the tool result, got %+v", gruftURL, last)
+		}
+		if result.Events[3].Screenshots != 1 || result.VisionMode != agent.VisionInstead {
+			t.Errorf("Expected the screenshot in the navigate event, got %+v", result.Events[3])
+		}
+	}
+}
+
+func TestRunToolError(t *testing.T) {
+	llm := llmstest.New("scripted",
+		llmstest.Step{Call: &llms.ToolCall{Name: "list_rooms", Arguments: `{"rooms":[]}`}},
+		llmstest.Step{Call: &llms.ToolCall{Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Thriller"}]}`}},
+		llmstest.Step{Rooms: []llms.Room{gruft}},
+	)
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	result, err := newTestAgent(t, llm, page, 5).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+	if len(result.Rooms) != 1 || result.ToolRetries != 2 {
+		t.Errorf("Expected the corrected call to list the room after 2 retries, got %v after %d", result.Rooms, result.ToolRetries)
+	}
+
+	// the failed calls are answered with the error, so the llm can correct them
+	for i, want := range []string{`unknown tool "list_rooms"`, `genre: "Thriller" is not one of`} {
+		last := llm.Conversations()[i+1].Last()
+		if last.Role != llms.RoleTool || !strings.Contains(last.Text, want) {
+			t.Errorf("Expected a tool result containing %q, got %+v", want, last)
+		}
+	}
+}
+
+func TestRunResumesFromStore(t *testing.T) {
+	st, err := store.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+	llm := llmstest.New("scripted", llmstest.Step{Rooms: []llms.Room{gruft}})
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	_, err = agent.New(llm, page, agent.WithStore(st)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+
+	// a finished run is answered from the store without prompting
+	result, err := agent.New(llmstest.New("scripted"), page, agent.WithStore(st)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error rerunning agent: %v", err)
+	}
+	if len(result.Rooms) != 1 || result.Steps != 1 {
+		t.Errorf("Expected the stored result, got %d rooms after %d steps", len(result.Rooms), result.Steps)
+	}
+}
+
+func TestRunDiscardsStateOfOtherSettings(t *testing.T) {
+	st, err := store.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	_, err = agent.New(llmstest.New("scripted", llmstest.Step{Rooms: []llms.Room{gruft}}), page, agent.WithStore(st)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+
+	// a run with another content format crawls again instead of answering from the store
+	llm := llmstest.New("scripted", llmstest.Step{Rooms: []llms.Room{labor}})
+	result, err := agent.New(llm, page, agent.WithStore(st), agent.WithContentFormat(scraper.FormatMarkdown)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error rerunning agent: %v", err)
+	}
+	if llm.Steps() != 1 || len(result.Rooms) != 1 || result.Rooms[0].Name != labor.Name || result.ContentFormat != string(scraper.FormatMarkdown) {
+		t.Errorf("Expected a new crawl with markdown, got %+v after %d steps", result.Rooms, llm.Steps())
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	st, err := store.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	// the run is cancelled while the pages of the first answer are loaded
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	llm := llmstest.New("scripted", llmstest.Step{URLs: []string{gruftURL}})
+	_, err = agent.New(llm, page, agent.WithStore(st), agent.WithEventHandler(func(event agent.Event) {
+		if event.Type == agent.EventPrompt {
+			cancel()
+		}
+	})).Run(ctx, provider)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the run to be interrupted, got %v", err)
+	}
+
+	state, err := st.CrawlState(providerURL, "scripted")
+	if err != nil {
+		t.Fatalf("Error loading crawl state: %v", err)
+	}
+	if state == nil || state.Done || state.Error != "" {
+		t.Fatalf("Expected an unfinished crawl state, got %+v", state)
+	}
+
+	// the resumed run repeats the interrupted step
+	resumed := llmstest.New("scripted", llmstest.Step{Rooms: []llms.Room{gruft}})
+	result, err := agent.New(resumed, page, agent.WithStore(st)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error resuming agent: %v", err)
+	}
+	if len(result.Rooms) != 1 || result.WebsitesChecked != 2 {
+		t.Errorf("Expected the resumed run to load the detail page and list the room, got %d rooms of %d websites", len(result.Rooms), result.WebsitesChecked)
+	}
+	last := resumed.Conversations()[0].Last()
+	if last.Role != llms.RoleTool || !strings.Contains(last.Text, gruftURL) {
+		t.Errorf("Expected the resumed prompt to answer with the detail page, got %+v", last)
+	}
+}
+
+func TestRunTrace(t *testing.T) {
+	dir := t.TempDir()
+	llm := llmstest.New("scripted",
+		llmstest.Step{URLs: []string{gruftURL}},
+		llmstest.Step{Rooms: []llms.Room{gruft}},
+	)
+	page, _ := scrapertest.NewBrowser(sitePages).CreatePage()
+
+	result, err := agent.New(llm, page, agent.WithTraceDir(dir)).Run(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("Error running agent: %v", err)
+	}
+
+	filename := agent.TracePath(dir, "scripted", providerURL)
+	if want := filepath.Join(dir, "scripted", "escape.example.com.jsonl"); filename != want {
+		t.Errorf("Expected trace %s, got %s", want, filename)
+	}
+	events, err := agent.ReadTrace(filename)
+	if err != nil {
+		t.Fatalf("Error reading trace: %v", err)
+	}
+	if len(events) != len(result.Events) {
+		t.Fatalf("Expected %d traced events, got %d", len(result.Events), len(events))
+	}
+
+	prompt := events[4]
+	if prompt.Type != agent.EventPrompt || len(prompt.Sent) != 1 || prompt.Sent[0].Role != llms.RoleTool || prompt.Sent[0].ToolName != llms.URLsName {
+		t.Fatalf("Expected the second prompt to send the tool result, got %+v", prompt)
+	}
+	if prompt.Received == nil || len(prompt.Received.ToolCalls) != 1 || prompt.Received.ToolCalls[0].Name != llms.RoomsName {
+		t.Errorf("Expected the rooms tool call to be received, got %+v", prompt.Received)
+	}
+}
+
+func newTestAgent(t *testing.T, llm llms.Plugin, page scraper.ScraperPage, limit int) *agent.Agent {
+	t.Helper()
+
+	st, err := store.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening store: %v", err)
+	}
+
+	return agent.New(llm, page,
+		agent.WithStore(st),
+		agent.WithLimit(limit),
+		agent.WithExtractionMode(agent.ExtractionConversation),
+		agent.WithContentFormat(scraper.FormatHTML),
+	)
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"go.uber.org/zap"
+)
+
+const (
+	chunkPrompt = "This is one part of a website. List all escape rooms mentioned in this part, even if some of their details are missing."
+	// chunkRetries is how often a chunk is prompted again after failed tool calls.
+	chunkRetries = 1
+)
+
+// extractChunked extracts the rooms of a page which is too large for a single request.
+// The content is split at DOM or paragraph boundaries into chunks within the limit, the rooms of
+// every chunk are extracted with a fresh conversation and merged afterwards.
+// The llm duration and the number of extracted chunks are added to the state of the run.
+func (r *run) extractChunked(ctx context.Context, url, content string, limit int) ([]llms.Room, error) {
+	chunks, err := scraper.SplitContent(content, r.contentFormat, limit, llms.EstimateTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split page: %w", err)
+	}
+
+	rooms := []llms.Room{}
+	for i, chunk := range chunks {
+		conversation := llms.NewConversation()
+		conversation.AddUser(fmt.Sprintf("%s Part %d of %d. %s", chunkPrompt, i+1, len(chunks), pageBlock(url, chunk)))
+
+		chunkRooms, err := r.extractChunk(ctx, conversation, url, i+1, len(chunks))
+		rooms = append(rooms, chunkRooms...)
+		if err != nil {
+			return llms.MergeRooms(rooms), err
+		}
+		r.state.ChunksExtracted++
+	}
+
+	return llms.MergeRooms(rooms), nil
+}
+
+// extractChunk prompts for the rooms of a chunk with only the rooms tool. Failed tool calls,
+// e.g. with dropped rooms, are answered with the error and the chunk is prompted again.
+func (r *run) extractChunk(ctx context.Context, conversation *llms.Conversation, url string, chunk, chunks int) ([]llms.Room, error) {
+	llm := r.llm
+	rooms := []llms.Room{}
+	for attempt := 0; ; attempt++ {
+		sent := conversation.Len()
+		llm.RoomToolOnly()
+		resp, duration, tokens, err := llm.ExecutePrompt(ctx, conversation)
+		llm.ResetChat()
+		r.state.LLMDuration += duration
+		r.state.TokenCount += tokens
+		event := Event{Type: EventChunk, URL: url, Chunk: chunk, Chunks: chunks, Duration: duration, Tokens: tokens}
+		event.Sent, event.Received = exchange(conversation, sent)
+		if err != nil {
+			err = fmt.Errorf("failed to extract chunk %d of %s: %w", chunk, url, err)
+			event.Err = err.Error()
+			r.emit(event)
+
+			return rooms, err
+		}
+
+		failed := false
+		for _, res := range resp {
+			rooms = append(rooms, res.Rooms...)
+			event.Rooms += len(res.Rooms)
+			failed = failed || res.Err != nil
+		}
+		r.emit(event)
+		if !failed {
+			return rooms, nil
+		}
+
+		for _, res := range resp {
+			if res.Err != nil {
+				r.logger.Warn("failed tool call", zap.String("url", url), zap.Int("chunk", chunk), zap.Error(res.Err))
+				r.emit(Event{Type: EventToolError, URL: url, Chunk: chunk, Chunks: chunks, Err: res.Err.Error()})
+			}
+		}
+		if attempt == chunkRetries {
+			return rooms, nil
+		}
+
+		// every tool call is answered, the failed ones with their error
+		for _, res := range resp {
+			if res.Err == nil {
+				addPrompt(conversation, "added", res)
+
+				continue
+			}
+			r.state.ToolRetries++
+			addPrompt(conversation, toolErrorBlock(res.Err), res)
+		}
+	}
+}
+
+// chunkedSummary replaces the content of a chunk-wise extracted page in the conversation.
+func chunkedSummary(rooms []llms.Room) string {
+	data, err := json.Marshal(llms.RoomsResp{Rooms: rooms})
+	if err != nil {
+		data = []byte("{}")
+	}
+
+	return fmt.Sprintf("The page was too large and was processed in parts. It contains these escape rooms: %s", data)
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+// EventType is the kind of an event.
+type EventType string
+
+const (
+	// EventStart is emitted when a run starts or is resumed.
+	EventStart EventType = "start"
+	// EventNavigate is emitted for every page the agent navigates to.
+	EventNavigate EventType = "navigate"
+	// EventSkip is emitted for every requested URL which is not navigated, because it was visited, is off-site or disallowed by robots.txt.
+	EventSkip EventType = "skip"
+	// EventPrompt is emitted for every llm request of the conversation.
+	EventPrompt EventType = "prompt"
+	// EventCompaction is emitted when page contents are dropped to fit the context window.
+	EventCompaction EventType = "compaction"
+	// EventChunk is emitted for every chunk of a page which is extracted on its own.
+	EventChunk EventType = "chunk"
+	// EventToolError is emitted for every tool call of the llm with an unknown tool or arguments which can't be repaired.
+	EventToolError EventType = "tool_error"
+	// EventFallback is emitted when the rooms are extracted from the visited pages after the conversation failed.
+	EventFallback EventType = "fallback"
+	// EventDone is emitted when the run is finished.
+	EventDone EventType = "done"
+)
+
+// Event is a step of a run.
+type Event struct {
+	// Step is the number of llm requests of the conversation before the event.
+	Step int       `json:"step"`
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+
+	Model         string        `json:"model,omitempty"`
+	Provider      string        `json:"provider,omitempty"`
+	URL           string        `json:"url,omitempty"`
+	URLs          []string      `json:"urls,omitempty"`
+	ContentLength int           `json:"content_length,omitempty"`
+	Consent       string        `json:"consent,omitempty"`
+	Screenshots   int           `json:"screenshots,omitempty"`
+	Chunk         int           `json:"chunk,omitempty"`
+	Chunks        int           `json:"chunks,omitempty"`
+	Rooms         int           `json:"rooms,omitempty"`
+	Tokens        int           `json:"tokens,omitempty"`
+	Duration      time.Duration `json:"duration,omitempty"`
+	Err           string        `json:"error,omitempty"`
+
+	// Sent are the turns added to the conversation since the previous request,
+	// Received is the answer of the llm. Images are left out.
+	Sent     []llms.Turn `json:"sent,omitempty"`
+	Received *llms.Turn  `json:"received,omitempty"`
+}
+
+// exchange returns the turns of the conversation sent in the last request, starting at index from,
+// and the answer of the llm if it was added to the conversation.
+func exchange(conversation *llms.Conversation, from int) ([]llms.Turn, *llms.Turn) {
+	turns := conversation.Turns
+	var received *llms.Turn
+	if last := conversation.Last(); last != nil && last.Role == llms.RoleAssistant && len(turns) > from {
+		turn := withoutImage(*last)
+		received = &turn
+		turns = turns[:len(turns)-1]
+	}
+
+	// the turns since the previous answer, the conversation may have been created before the run
+	start := min(from, len(turns))
+	for start > 0 && turns[start-1].Role != llms.RoleAssistant {
+		start--
+	}
+
+	sent := make([]llms.Turn, 0, len(turns)-start)
+	for _, turn := range turns[start:] {
+		sent = append(sent, withoutImage(turn))
+	}
+
+	return sent, received
+}
+
+func withoutImage(turn llms.Turn) llms.Turn {
+	turn.Images = nil
+
+	return turn
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// BookingPlatforms are the registrable domains of booking platforms escape room providers embed or link to.
+var BookingPlatforms = []string{
+	"bookeo.com",
+	"bookingkit.de",
+	"bookingkit.net",
+	"escaperoommaster.com",
+	"fareharbor.com",
+	"regiondo.com",
+	"regiondo.de",
+	"resova.com",
+	"simplybook.it",
+	"simplybook.me",
+	"xola.com",
+}
+
+// trackingParams are query parameters which do not change the content of a page.
+var trackingParams = []string{"fbclid", "gclid", "mc_cid", "mc_eid", "msclkid"}
+
+var (
+	// ErrOffsite is returned for URLs outside of the website of the provider and the allowed domains.
+	ErrOffsite = errors.New("url is not part of the website")
+	// ErrVisited is returned for URLs whose page was already sent to the llm.
+	ErrVisited = errors.New("page already visited")
+)
+
+// Frontier decides which of the URLs requested by the llm are navigated.
+// It restricts the crawl to the registrable domain of the start URL and the allowed domains,
+// and skips pages which were already visited.
+type Frontier struct {
+	domain  string
+	allowed []string
+
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// NewFrontier creates the frontier of a crawl starting at the start URL. Subdomains of the allowed domains are allowed too.
+func NewFrontier(startURL string, allowed []string) (*Frontier, error) {
+	start, err := Canonicalize(startURL)
+	if err != nil {
+		return nil, err
+	}
+
+	u, _ := url.Parse(start)
+
+	return &Frontier{
+		domain:  registrableDomain(u.Hostname()),
+		allowed: allowed,
+		visited: make(map[string]bool),
+	}, nil
+}
+
+// Check returns the canonical URL to navigate to. It fails with ErrOffsite or ErrVisited
+// if the URL is skipped, and with another error if it is no valid http(s) URL.
+func (f *Frontier) Check(rawURL string) (string, error) {
+	canonical, err := Canonicalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u, _ := url.Parse(canonical)
+	if !f.inScope(u.Hostname()) {
+		return canonical, fmt.Errorf("%w: %s", ErrOffsite, canonical)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.visited[canonical] {
+		return canonical, fmt.Errorf("%w: %s", ErrVisited, canonical)
+	}
+
+	return canonical, nil
+}
+
+// Resolve resolves the reference, e.g. a relative URL requested by the llm, against the URL of the page it was found on.
+// References which can't be parsed are returned as they are, so Check reports them.
+func Resolve(pageURL, ref string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+
+	return base.ResolveReference(u).String()
+}
+
+// Visit marks the canonical URL as visited.
+func (f *Frontier) Visit(canonical string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.visited[canonical] = true
+}
+
+func (f *Frontier) inScope(host string) bool {
+	domain := registrableDomain(host)
+	if domain == f.domain {
+		return true
+	}
+
+	return slices.ContainsFunc(f.allowed, func(allowed string) bool {
+		return host == allowed || strings.HasSuffix(host, "."+allowed)
+	})
+}
+
+// registrableDomain returns the public suffix plus one label of the host, e.g. example.co.uk for www.example.co.uk.
+// Hosts without one, like IP addresses or localhost, are returned as they are.
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+
+	return domain
+}
+
+// Canonicalize normalizes an absolute http(s) URL so that URLs of the same page are equal:
+// the scheme and host are lowercased, default ports, fragments, tracking parameters
+// and trailing slashes are removed and the query parameters are sorted.
+func Canonicalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("not an absolute http(s) url: %s", rawURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for param := range query {
+		if strings.HasPrefix(param, "utm_") || slices.Contains(trackingParams, param) {
+			query.Del(param)
+		}
+	}
+	// Encode sorts by key
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
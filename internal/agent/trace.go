@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// TracePath returns the trace file of the run of the model on the provider within the directory.
+// Resumed runs append to the trace of the interrupted run.
+func TracePath(dir, model, providerURL string) string {
+	name := providerURL
+	u, err := url.Parse(providerURL)
+	if err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+
+	return filepath.Join(dir, unsafeFileChars.ReplaceAllString(model, "_"), name+".jsonl")
+}
+
+// ReadTrace reads the events of a trace file.
+func ReadTrace(filename string) ([]Event, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer file.Close()
+
+	events := []Event{}
+	scanner := bufio.NewScanner(file)
+	// events carry whole pages
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		err = json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trace event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+
+	return events, nil
+}
+
+// traceWriter appends events as JSON lines to a trace file.
+type traceWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	err  error
+}
+
+func openTrace(filename string) (*traceWriter, error) {
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+
+	return &traceWriter{
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+// write appends the event, after the first error further events are dropped.
+func (t *traceWriter) write(event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	t.err = t.enc.Encode(event)
+}
+
+// Close closes the trace file and returns the first write error.
+func (t *traceWriter) Close() error {
+	err := t.file.Close()
+	if t.err != nil {
+		return fmt.Errorf("failed to write trace: %w", t.err)
+	}
+
+	return err
+}
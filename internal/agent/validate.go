@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+// addRooms adds the rooms found on the page, resolving their URLs against the page URL.
+func (r *run) addRooms(pageURL string, rooms []llms.Room) {
+	r.state.InvalidURLs += validateRoomURLs(pageURL, rooms)
+	r.state.Rooms = append(r.state.Rooms, rooms...)
+}
+
+// lastPageURL returns the URL of the page loaded last, the source of the rooms the llm answers with.
+func (r *run) lastPageURL() string {
+	if len(r.state.Pages) == 0 {
+		return r.state.ProviderURL
+	}
+
+	return r.state.Pages[len(r.state.Pages)-1].URL
+}
+
+// validateRoomURLs ensures the booking, detail page and image URLs of the rooms are absolute and well-formed.
+// Relative URLs are resolved against the URL of the page the rooms were found on, malformed URLs are removed.
+// It returns the number of URLs which were not absolute and well-formed.
+func validateRoomURLs(pageURL string, rooms []llms.Room) int {
+	base, err := url.Parse(pageURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	invalid := 0
+	for i := range rooms {
+		for _, field := range []*string{&rooms[i].BookingURL, &rooms[i].DetailPageURL, &rooms[i].ImageURL} {
+			if *field == "" {
+				continue
+			}
+
+			valid, ok := validURL(base, *field)
+			if !ok || valid != *field {
+				invalid++
+			}
+			*field = valid
+		}
+	}
+
+	return invalid
+}
+
+// validURL returns the absolute http(s) URL of the reference and whether it could be repaired.
+func validURL(base *url.URL, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false
+	}
+
+	if !u.IsAbs() {
+		if base == nil {
+			return "", false
+		}
+		u = base.ResolveReference(u)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+
+	return u.String(), true
+}
@@ -0,0 +1,228 @@
+// Package evaluation scores the scraped escape rooms of a run against hand-labeled ground truth.
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/identity"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+)
+
+// Provider is the labeled ground truth of an escape room provider.
+type Provider struct {
+	Name  string      `json:"name"`
+	URL   string      `json:"url"`
+	Rooms []llms.Room `json:"rooms"`
+}
+
+// Result holds the scores of a single run.
+type Result struct {
+	Model string
+	Mode  string
+
+	// Providers is the number of ground truth providers found in the run.
+	Providers     int
+	ExpectedRooms int
+	FoundRooms    int
+	MatchedRooms  int
+	Precision     float64
+	Recall        float64
+
+	// The field accuracies are the shares of matched rooms with a labeled value whose value is correct.
+	// Shares without anything to score are NaN.
+	PlayersAccuracy    float64
+	DurationAccuracy   float64
+	GenreAccuracy      float64
+	DifficultyAccuracy float64
+
+	// URLValidity is the share of booking, detail page and image URLs which are absolute and well-formed.
+	URLValidity float64
+
+	// TokenCount is the sum of the token counts of all providers.
+	TokenCount int
+	// LLMDuration and RequestDuration are the mean durations per provider.
+	LLMDuration     time.Duration
+	RequestDuration time.Duration
+}
+
+// LoadGroundTruth reads the labeled providers from a JSON file.
+func LoadGroundTruth(filename string) ([]Provider, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var providers []Provider
+	err = json.Unmarshal(data, &providers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	return providers, nil
+}
+
+// ParseRunName derives the mode and the model of a run from its CSV file name,
+// e.g. "guided-gpt-4o-minioutput.csv" is the guided run of gpt-4o-mini and
+// "gpt-4o-mini-strictoutput.csv", named after the model of the strict mode, its strict run.
+func ParseRunName(filename string) (mode, model string) {
+	name := strings.TrimSuffix(filepath.Base(filename), ".csv")
+	name = strings.TrimSuffix(name, "output")
+
+	for _, m := range []string{"freechoice", "guided"} {
+		if model, ok := strings.CutPrefix(name, m+"-"); ok {
+			return m, model
+		}
+	}
+	if model, ok := strings.CutSuffix(name, "-strict"); ok {
+		return "strict", model
+	}
+
+	return "", name
+}
+
+// Evaluate scores the rows of a run against the ground truth.
+// Providers of the ground truth missing in the run count as providers without found rooms.
+func Evaluate(mode, model string, information []output.Information, truth []Provider) Result {
+	result := Result{
+		Model: model,
+		Mode:  mode,
+	}
+
+	var players, duration, genre, difficulty score
+	var urls score
+	var llmDuration, requestDuration time.Duration
+	for _, provider := range truth {
+		rows := providerRows(information, provider.URL)
+		result.ExpectedRooms += len(provider.Rooms)
+		if len(rows) == 0 {
+			continue
+		}
+
+		result.Providers++
+		// every row of a provider holds the tokens of all prompts of its crawl
+		result.TokenCount += rows[0].TokenCount
+		llmDuration += rows[0].LLMDuration
+		requestDuration += rows[0].RequestDuration
+
+		matched := make([]bool, len(provider.Rooms))
+		for _, row := range rows {
+			if row.RoomName == "" {
+				continue
+			}
+			result.FoundRooms++
+
+			for _, u := range []string{row.BookingURL, row.DetailPageURL, row.ImageURL} {
+				if u != "" {
+					urls.add(isAbsoluteURL(u))
+				}
+			}
+
+			index := matchRoom(provider.Rooms, matched, row)
+			if index == -1 {
+				continue
+			}
+			matched[index] = true
+			result.MatchedRooms++
+
+			expected := provider.Rooms[index]
+			if expected.PlayersMin != 0 || expected.PlayersMax != 0 {
+				players.add(expected.PlayersMin == row.MinPlayers && expected.PlayersMax == row.MaxPlayers)
+			}
+			if expected.Duration != 0 {
+				duration.add(expected.Duration == row.Duration)
+			}
+			if expected.Genre != "" {
+				genre.add(strings.EqualFold(expected.Genre, strings.TrimSpace(row.Genre)))
+			}
+			if expected.Difficulty != "" {
+				difficulty.add(strings.EqualFold(expected.Difficulty, strings.TrimSpace(row.Difficulty)))
+			}
+		}
+	}
+
+	result.Precision = ratio(result.MatchedRooms, result.FoundRooms)
+	result.Recall = ratio(result.MatchedRooms, result.ExpectedRooms)
+	result.PlayersAccuracy = players.value()
+	result.DurationAccuracy = duration.value()
+	result.GenreAccuracy = genre.value()
+	result.DifficultyAccuracy = difficulty.value()
+	result.URLValidity = urls.value()
+	if result.Providers > 0 {
+		result.LLMDuration = llmDuration / time.Duration(result.Providers)
+		result.RequestDuration = requestDuration / time.Duration(result.Providers)
+	}
+
+	return result
+}
+
+// providerRows returns the rows of the provider. Runs may contain a provider several times,
+// only the rows of its first ID are used.
+func providerRows(information []output.Information, providerURL string) []output.Information {
+	rows := []output.Information{}
+	for _, info := range information {
+		if !identity.SameURL(info.ProviderURL, providerURL) {
+			continue
+		}
+		if len(rows) > 0 && rows[0].ID != info.ID {
+			continue
+		}
+
+		rows = append(rows, info)
+	}
+
+	return rows
+}
+
+// matchRoom returns the index of the unmatched ground truth room of the row or -1.
+func matchRoom(rooms []llms.Room, matched []bool, row output.Information) int {
+	for i, room := range rooms {
+		if matched[i] {
+			continue
+		}
+
+		if identity.Same(llms.RoomIdentity(room), identity.Room{Name: row.RoomName, DetailPageURL: row.DetailPageURL}) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isAbsoluteURL(ref string) bool {
+	u, err := url.Parse(ref)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// score counts correct values.
+type score struct {
+	correct int
+	total   int
+}
+
+func (s *score) add(correct bool) {
+	s.total++
+	if correct {
+		s.correct++
+	}
+}
+
+func (s score) value() float64 {
+	return ratio(s.correct, s.total)
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return math.NaN()
+	}
+
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,78 @@
+package evaluation_test
+
+import (
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/evaluation"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/output"
+)
+
+func TestParseRunName(t *testing.T) {
+	tests := []struct {
+		filename string
+		mode     string
+		model    string
+	}{
+		{"csvs/guided-gpt-4o-minioutput.csv", "guided", "gpt-4o-mini"},
+		{"freechoice-gemini-1.5-pro-001output.csv", "freechoice", "gemini-1.5-pro-001"},
+		{"gpt-4o-mini-strictoutput.csv", "strict", "gpt-4o-mini"},
+		{"claude-3-5-sonnet-20240620output.csv", "", "claude-3-5-sonnet-20240620"},
+	}
+
+	for _, tt := range tests {
+		mode, model := evaluation.ParseRunName(tt.filename)
+		if mode != tt.mode || model != tt.model {
+			t.Errorf("ParseRunName(%q) = %q, %q, want %q, %q", tt.filename, mode, model, tt.mode, tt.model)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	truth := []evaluation.Provider{
+		{
+			Name: "Final Escape Berlin",
+			URL:  "https://final-escape.com/berlin/",
+			Rooms: []llms.Room{
+				{Name: "Blutiges Erwachen", PlayersMin: 3, PlayersMax: 6, Duration: 60, Genre: "Horror"},
+				{Name: "Prison Break", PlayersMin: 2, PlayersMax: 6, Duration: 60, Genre: "Prison"},
+			},
+		},
+		{
+			Name:  "60 MINUTES",
+			URL:   "https://www.60-minutes.de/",
+			Rooms: []llms.Room{{Name: "Der Fluch"}},
+		},
+	}
+	information := []output.Information{
+		{ProviderURL: "https://final-escape.com/berlin", RoomName: "blutiges  erwachen", MinPlayers: 3, MaxPlayers: 6, Duration: 60, Genre: "Horror", BookingURL: "/berlin/gutscheine/", TokenCount: 100},
+		{ProviderURL: "https://final-escape.com/berlin", RoomName: "Gutschein", Genre: "Adventure", TokenCount: 100},
+	}
+
+	result := evaluation.Evaluate("guided", "gpt-4o-mini", information, truth)
+	if result.Providers != 1 || result.ExpectedRooms != 3 || result.FoundRooms != 2 || result.MatchedRooms != 1 {
+		t.Fatalf("Unexpected counts: %+v", result)
+	}
+	if result.Precision != 0.5 || result.Recall != 1.0/3 {
+		t.Errorf("Expected precision 0.5 and recall 0.33, got %f and %f", result.Precision, result.Recall)
+	}
+	if result.PlayersAccuracy != 1 || result.GenreAccuracy != 1 {
+		t.Errorf("Expected correct fields of the matched room, got %+v", result)
+	}
+	if result.URLValidity != 0 {
+		t.Errorf("Expected the relative booking URL to be invalid, got %f", result.URLValidity)
+	}
+	if result.TokenCount != 100 {
+		t.Errorf("Expected the token count of the provider, got %d", result.TokenCount)
+	}
+}
+
+func TestLoadGroundTruthSample(t *testing.T) {
+	truth, err := evaluation.LoadGroundTruth("../../groundTruth.sample.json")
+	if err != nil {
+		t.Fatalf("Error loading the sample ground truth: %v", err)
+	}
+	if len(truth) != 1 || len(truth[0].Rooms) != 2 || truth[0].Rooms[0].PlayersMin != 3 {
+		t.Errorf("Unexpected ground truth %+v", truth)
+	}
+}
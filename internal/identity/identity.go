@@ -0,0 +1,155 @@
+// Package identity decides whether two scraped records describe the same escape room.
+package identity
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Threshold is the minimum similarity of two normalized names to be the same room.
+const Threshold = 0.85
+
+// Room holds the fields identifying an escape room.
+type Room struct {
+	Name          string
+	DetailPageURL string
+}
+
+var replacer = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"á", "a", "à", "a", "â", "a", "é", "e", "è", "e", "ê", "e",
+	"í", "i", "ì", "i", "î", "i", "ó", "o", "ò", "o", "ô", "o",
+	"ú", "u", "ù", "u", "û", "u", "ç", "c", "ñ", "n",
+	"&", " und ",
+)
+
+// affixes are removed from the start and the end of normalized names, longest first.
+var affixes = []string{
+	"live escape game",
+	"escape rooms",
+	"escape room",
+	"escape game",
+	"escaperoom",
+	"exit game",
+	"raetselraum",
+}
+
+// NormalizeName lowercases the name, transliterates umlauts and accents, removes
+// punctuation and affixes like "Escape Room", e.g. "Die Gruft – Escape Room" is "die gruft".
+func NormalizeName(name string) string {
+	name = replacer.Replace(strings.ToLower(name))
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, affix := range affixes {
+			if rest, ok := strings.CutSuffix(name, " "+affix); ok {
+				name, trimmed = rest, true
+			}
+			if rest, ok := strings.CutPrefix(name, affix+" "); ok {
+				name, trimmed = rest, true
+			}
+		}
+	}
+
+	return name
+}
+
+// NormalizeURL returns the host and path of the URL without scheme, "www.", query and trailing slash.
+func NormalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(strings.TrimSuffix(rawURL, "/"))
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+
+	return host + strings.TrimSuffix(u.Path, "/")
+}
+
+// SameURL reports whether both URLs point to the same page.
+func SameURL(a, b string) bool {
+	return a != "" && b != "" && NormalizeURL(a) == NormalizeURL(b)
+}
+
+// Same reports whether both records are the same room. Many websites list several rooms on one
+// overview page, so rooms with the same detail page are only the same if one name is missing or
+// the names are similar or one contains the other. Rooms with different detail pages are only the
+// same if their normalized names are equal. Otherwise the names have to be similar, see similarNames.
+func Same(a, b Room) bool {
+	nameA, nameB := NormalizeName(a.Name), NormalizeName(b.Name)
+	if SameURL(a.DetailPageURL, b.DetailPageURL) {
+		return nameA == "" || nameB == "" || containsName(nameA, nameB) || similarNames(nameA, nameB)
+	}
+
+	if nameA == "" || nameB == "" {
+		return false
+	}
+	if nameA == nameB {
+		return true
+	}
+	if a.DetailPageURL != "" && b.DetailPageURL != "" {
+		return false
+	}
+
+	return similarNames(nameA, nameB)
+}
+
+// similarNames reports whether the normalized names are similar and contain the same numbers,
+// so "Pirates 1" and "Pirates 2" stay different rooms.
+func similarNames(a, b string) bool {
+	return numbers(a) == numbers(b) && Similarity(a, b) >= Threshold
+}
+
+// containsName reports whether one normalized name contains all words of the other,
+// e.g. "horror hospital" contains "horror".
+func containsName(a, b string) bool {
+	if numbers(a) != numbers(b) {
+		return false
+	}
+
+	return strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ")
+}
+
+// Similarity returns the similarity of two strings between 0 and 1 based on their Levenshtein distance.
+func Similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	length := max(len(ra), len(rb))
+	if length == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(length)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+func numbers(name string) string {
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	}), " ")
+}
@@ -0,0 +1,51 @@
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/identity"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Die Gruft – Escape Room", "die gruft"},
+		{"  Blutiges   Erwachen ", "blutiges erwachen"},
+		{"Escape Room: Das Geheimnis der Mühle", "das geheimnis der muehle"},
+		{"Prison Break!", "prison break"},
+		{"Café Noir - Live Escape Game", "cafe noir"},
+	}
+
+	for _, tt := range tests {
+		if got := identity.NormalizeName(tt.name); got != tt.want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSame(t *testing.T) {
+	tests := []struct {
+		a, b identity.Room
+		want bool
+	}{
+		{identity.Room{Name: "Die Gruft"}, identity.Room{Name: "DIE GRUFT – Escape Room"}, true},
+		{identity.Room{Name: "Das verlassene Kraftwerk"}, identity.Room{Name: "Das verlassene Krafwerk"}, true},
+		{identity.Room{Name: "Pirates 1"}, identity.Room{Name: "Pirates 2"}, false},
+		{identity.Room{Name: "Horror", DetailPageURL: "https://www.example.com/horror/"}, identity.Room{Name: "Horror Hospital", DetailPageURL: "http://example.com/horror"}, true},
+		{identity.Room{Name: "Grabkammer", DetailPageURL: "https://example.com/a"}, identity.Room{Name: "Grabkamer", DetailPageURL: "https://example.com/b"}, false},
+		{identity.Room{Name: "Grabkammer"}, identity.Room{Name: "Piraten"}, false},
+		// overview pages list several rooms under one URL
+		{identity.Room{Name: "Der Fluch des Pharao", DetailPageURL: "https://harz-escape.de/index_en.html#games"}, identity.Room{Name: "Das Labor", DetailPageURL: "https://harz-escape.de/index_en.html#games"}, false},
+		{identity.Room{Name: "Mission 1", DetailPageURL: "https://adventurerooms.de/dresden/"}, identity.Room{Name: "Mission 2", DetailPageURL: "https://adventurerooms.de/dresden/"}, false},
+		{identity.Room{DetailPageURL: "https://example.com/horror"}, identity.Room{Name: "Horror Hospital", DetailPageURL: "https://example.com/horror"}, true},
+		{identity.Room{}, identity.Room{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := identity.Same(tt.a, tt.b); got != tt.want {
+			t.Errorf("Same(%+v, %+v) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package claude
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -15,6 +14,13 @@ import (
 
 var _ = (llms.Plugin)(&claude{})
 
+// contextWindows holds the context window sizes of the known models in tokens.
+var contextWindows = map[string]int{
+	"claude-3-5-sonnet-20240620": 200000,
+}
+
+const defaultContextWindow = 200000
+
 type toolChoice struct {
 	Type string `json:"type"`
 	Name string `json:"name,omitempty"`
@@ -24,35 +30,28 @@ type claude struct {
 	imageSupport bool
 	tools        []langchain.Tool
 	model        *anthropic.LLM
-	messages     []langchain.MessageContent
-	toolChoice   toolChoice
 	modelName    string
+	roomToolOnly bool
 	guided       bool
 }
 
-func New(modelName string, token string, imageSupport bool) llms.Plugin {
-	llm, err := anthropic.New(anthropic.WithModel(modelName), anthropic.WithToken(token))
+// New creates the plugin of the model. The options are passed to the anthropic client, e.g. to set the base URL.
+func New(modelName string, token string, imageSupport bool, opts ...anthropic.Option) llms.Plugin {
+	llm, err := anthropic.New(append([]anthropic.Option{anthropic.WithModel(modelName), anthropic.WithToken(token)}, opts...)...)
 	if err != nil {
 		panic(fmt.Errorf("failed to create LLM: %w", err))
 	}
 
-	tools := []langchain.Tool{
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.RoomsName,
-				Description: llms.RoomsDescription,
-				Parameters:  generateSchemaMap(llms.RoomsResp{}),
-			},
-		},
-		{
+	tools := []langchain.Tool{}
+	for _, tool := range llms.Tools.All() {
+		tools = append(tools, langchain.Tool{
 			Type: "function",
 			Function: &langchain.FunctionDefinition{
-				Name:        llms.URLsName,
-				Description: llms.URLsDescription,
-				Parameters:  generateSchemaMap(llms.UrlsResp{}),
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.Schema().Map(),
 			},
-		},
+		})
 	}
 
 	return &claude{
@@ -60,57 +59,85 @@ func New(modelName string, token string, imageSupport bool) llms.Plugin {
 		imageSupport: imageSupport,
 		model:        llm,
 		modelName:    modelName,
-		toolChoice: toolChoice{
-			Type: "tool",
-			Name: llms.URLsName,
-		},
 	}
 }
 
-func (c *claude) AddPrompt(image []byte, text, chatID, toolName string) {
-	content := []langchain.ContentPart{
-		langchain.TextPart(text),
+func (c *claude) toolChoice(conversation *llms.Conversation) toolChoice {
+	if c.roomToolOnly {
+		return toolChoice{
+			Type: "tool",
+			Name: llms.RoomsName,
+		}
 	}
 
-	if c.guided {
-		c.toolChoice = toolChoice{
-			Type: "function",
+	if c.guided && !conversation.HasAssistant() {
+		return toolChoice{
+			Type: "tool",
 			Name: llms.URLsName,
 		}
 	}
 
-	if chatID != "" {
-		c.toolChoice = toolChoice{
-			Type: "any",
-		}
-
-		content = []langchain.ContentPart{
-			langchain.ToolCallResponse{
-				ToolCallID: chatID,
-				Name:       toolName,
-				Content:    text,
-			},
-		}
+	return toolChoice{
+		Type: "any",
 	}
+}
 
-	if len(image) > 0 {
-		content = append(content, langchain.BinaryPart("image/webp", image))
-	}
+// messages translates the conversation to the langchain message format.
+// Anthropic only reads the first part of a message, so every tool call gets its own message.
+func (c *claude) messages(conversation *llms.Conversation) []langchain.MessageContent {
+	messages := []langchain.MessageContent{}
+	for _, turn := range conversation.Turns {
+		switch turn.Role {
+		case llms.RoleSystem:
+			messages = append(messages, langchain.TextParts(langchain.ChatMessageTypeSystem, turn.Text))
+		case llms.RoleUser:
+			content := []langchain.ContentPart{
+				langchain.TextPart(turn.Text),
+			}
+			for _, image := range turn.Images {
+				content = append(content, langchain.BinaryPart("image/webp", image))
+			}
 
-	role := langchain.ChatMessageTypeHuman
-	if len(c.messages) > 0 {
-		role = langchain.ChatMessageTypeTool
+			messages = append(messages, langchain.MessageContent{
+				Role:  langchain.ChatMessageTypeHuman,
+				Parts: content,
+			})
+		case llms.RoleAssistant:
+			for _, toolCall := range turn.ToolCalls {
+				messages = append(messages, langchain.MessageContent{
+					Role: langchain.ChatMessageTypeAI,
+					Parts: []langchain.ContentPart{
+						langchain.ToolCall{
+							ID:   toolCall.ID,
+							Type: "function",
+							FunctionCall: &langchain.FunctionCall{
+								Name:      toolCall.Name,
+								Arguments: toolCall.Arguments,
+							},
+						},
+					},
+				})
+			}
+		case llms.RoleTool:
+			messages = append(messages, langchain.MessageContent{
+				Role: langchain.ChatMessageTypeTool,
+				Parts: []langchain.ContentPart{
+					langchain.ToolCallResponse{
+						ToolCallID: turn.ToolCallID,
+						Name:       turn.ToolName,
+						Content:    turn.Text,
+					},
+				},
+			})
+		}
 	}
 
-	c.messages = append(c.messages, langchain.MessageContent{
-		Role:  role,
-		Parts: content,
-	})
+	return messages
 }
 
-func (c *claude) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+func (c *claude) ExecutePrompt(ctx context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
 	startTime := time.Now()
-	resp, err := c.model.GenerateContent(ctx, c.messages, langchain.WithTools(c.tools), langchain.WithToolChoice(c.toolChoice), langchain.WithMaxTokens(8192))
+	resp, err := c.model.GenerateContent(ctx, c.messages(conversation), langchain.WithTools(c.tools), langchain.WithToolChoice(c.toolChoice(conversation)), langchain.WithMaxTokens(8192))
 	duration := time.Since(startTime)
 	if err != nil {
 		return nil, duration, 0, err
@@ -122,47 +149,22 @@ func (c *claude) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID
 
 	llmResponseWithChatID := []llms.LlmResposeWithChatID{}
 
+	toolCalls := []llms.ToolCall{}
 	for _, choice := range resp.Choices {
 		for _, toolCall := range choice.ToolCalls {
-			assistantResponse := langchain.MessageContent{
-				Role: langchain.ChatMessageTypeAI,
-				Parts: []langchain.ContentPart{
-					langchain.ToolCall{
-						ID:   toolCall.ID,
-						Type: toolCall.Type,
-						FunctionCall: &langchain.FunctionCall{
-							Name:      toolCall.FunctionCall.Name,
-							Arguments: toolCall.FunctionCall.Arguments,
-						},
-					},
-				},
-			}
-			c.messages = append(c.messages, assistantResponse)
-
-			var llmResponse interface{} = &llms.RoomsResp{}
-			if toolCall.FunctionCall.Name == llms.URLsName {
-				llmResponse = &llms.UrlsResp{}
-			}
-
-			err = json.Unmarshal([]byte(toolCall.FunctionCall.Arguments), &llmResponse)
-			if err != nil {
-				return nil, duration, 0, fmt.Errorf("failed to unmarshal response: %w", err)
-			}
-
-			result := llms.LlmResposeWithChatID{
-				ToolName: toolCall.FunctionCall.Name,
-				ChatID:   toolCall.ID,
-			}
-
-			if urls, ok := llmResponse.(*llms.UrlsResp); ok {
-				result.URLs = urls.URLs
-			} else if rooms, ok := llmResponse.(*llms.RoomsResp); ok {
-				result.Rooms = rooms.Rooms
+			call := llms.ToolCall{
+				ID:        toolCall.ID,
+				Name:      toolCall.FunctionCall.Name,
+				Arguments: toolCall.FunctionCall.Arguments,
 			}
+			toolCalls = append(toolCalls, call)
 
+			result, err := llms.Tools.Decode(call)
+			result.Err = err
 			llmResponseWithChatID = append(llmResponseWithChatID, result)
 		}
 	}
+	conversation.AddAssistant(resp.Choices[0].Content, toolCalls...)
 
 	return llmResponseWithChatID, duration, 0, nil
 }
@@ -175,19 +177,21 @@ func (c *claude) ImageSupport() bool {
 	return c.imageSupport
 }
 
-func (c *claude) ResetChat() {
-	c.toolChoice = toolChoice{
-		Type: "any",
+func (c *claude) ContextWindow() int {
+	if window, ok := contextWindows[c.modelName]; ok {
+		return window
 	}
-	c.messages = nil
+
+	return defaultContextWindow
+}
+
+func (c *claude) ResetChat() {
+	c.roomToolOnly = false
 }
 
 func (c *claude) RoomToolOnly() {
 	c.guided = false
-	c.toolChoice = toolChoice{
-		Type: "tool",
-		Name: llms.RoomsName,
-	}
+	c.roomToolOnly = true
 }
 
 func (c *claude) Guided(mode bool) {
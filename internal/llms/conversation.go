@@ -0,0 +1,119 @@
+package llms
+
+type Role string // Role is the author of a turn in a conversation.
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+)
+
+// ToolCall is a function call requested by the llm. Arguments holds the raw JSON arguments.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Turn is a single message of a conversation. Assistant turns carry the requested
+// tool calls, tool turns the result of the tool call referenced by ToolCallID.
+type Turn struct {
+	Role       Role       `json:"role"`
+	Text       string     `json:"text,omitempty"`
+	Images     [][]byte   `json:"images,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolName   string     `json:"tool_name,omitempty"`
+}
+
+// Conversation is the provider independent chat history. The plugins translate it
+// to their wire format on every request, so it can be inspected, persisted,
+// truncated and replayed by the orchestrator.
+type Conversation struct {
+	Turns []Turn `json:"turns"`
+}
+
+func NewConversation() *Conversation {
+	return &Conversation{}
+}
+
+func (c *Conversation) AddSystem(text string) {
+	c.Turns = append(c.Turns, Turn{
+		Role: RoleSystem,
+		Text: text,
+	})
+}
+
+// AddUser adds a user turn with optional WebP images.
+func (c *Conversation) AddUser(text string, images ...[]byte) {
+	c.Turns = append(c.Turns, Turn{
+		Role:   RoleUser,
+		Text:   text,
+		Images: images,
+	})
+}
+
+// AddAssistant adds the answer of the llm. Text is only set if the llm answered without a tool call.
+func (c *Conversation) AddAssistant(text string, toolCalls ...ToolCall) {
+	c.Turns = append(c.Turns, Turn{
+		Role:      RoleAssistant,
+		Text:      text,
+		ToolCalls: toolCalls,
+	})
+}
+
+func (c *Conversation) AddToolResult(toolCallID, toolName, text string) {
+	c.Turns = append(c.Turns, Turn{
+		Role:       RoleTool,
+		Text:       text,
+		ToolCallID: toolCallID,
+		ToolName:   toolName,
+	})
+}
+
+// Last returns the last turn of the conversation or nil if it is empty.
+func (c *Conversation) Last() *Turn {
+	if len(c.Turns) == 0 {
+		return nil
+	}
+
+	return &c.Turns[len(c.Turns)-1]
+}
+
+// HasAssistant reports whether the llm already answered in this conversation.
+func (c *Conversation) HasAssistant() bool {
+	for _, turn := range c.Turns {
+		if turn.Role == RoleAssistant {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Truncate drops all turns after the first n turns.
+func (c *Conversation) Truncate(n int) {
+	if n < len(c.Turns) {
+		c.Turns = c.Turns[:n]
+	}
+}
+
+func (c *Conversation) Len() int {
+	return len(c.Turns)
+}
+
+func (c *Conversation) Reset() {
+	c.Turns = nil
+}
+
+func (c *Conversation) Clone() *Conversation {
+	turns := make([]Turn, len(c.Turns))
+	for i, turn := range c.Turns {
+		turn.ToolCalls = append([]ToolCall(nil), turn.ToolCalls...)
+		turn.Images = append([][]byte(nil), turn.Images...)
+		turns[i] = turn
+	}
+
+	return &Conversation{Turns: turns}
+}
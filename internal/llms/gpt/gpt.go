@@ -2,7 +2,7 @@ package gpt
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -12,14 +12,42 @@ import (
 
 var _ = (llms.Plugin)(&gpt{})
 
+// contextWindows holds the context window sizes of the known models in tokens.
+var contextWindows = map[string]int{
+	openai.GPT4o:     128000,
+	openai.GPT4oMini: 128000,
+}
+
+const (
+	defaultContextWindow = 128000
+	// StrictSuffix is appended to the model name in strict mode.
+	StrictSuffix = "-strict"
+	// strictPrompt tells the llm how to end the conversation in strict mode, where the rooms are no tool.
+	strictPrompt = "To list the escape rooms and end the conversation, answer with the escape rooms instead of calling a tool."
+)
+
 type gpt struct {
 	client       *openai.Client
 	imageSupport bool
 	model        string
 	tools        []openai.Tool
-	toolChoice   any
+	roomToolOnly bool
 	guided       bool
-	messages     []openai.ChatCompletionMessage
+	strict       bool
+	// roomsFormat is the response format of the rooms in strict mode.
+	roomsFormat *openai.ChatCompletionResponseFormat
+}
+
+// Option configures the gpt plugin.
+type Option func(*gpt)
+
+// WithStrict uses structured outputs: the tools are declared with strict schemas and the rooms are
+// listed as answer with a strict JSON schema response format, so they always match llms.RoomsResp.
+// The model name gets the suffix StrictSuffix, so strict runs are stored and written apart from the others.
+func WithStrict() Option {
+	return func(g *gpt) {
+		g.strict = true
+	}
 }
 
 type functionChoice struct {
@@ -29,78 +57,182 @@ type functionChoice struct {
 	} `json:"function"`
 }
 
-func New(client *openai.Client, model string, imageSupport bool) llms.Plugin {
-	t := []openai.Tool{
-		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        llms.RoomsName,
-				Description: llms.RoomsDescription,
-				Parameters:  generateSchema(llms.RoomsResp{}),
-			},
-		},
-		{
-			Type: openai.ToolTypeFunction,
-			Function: &openai.FunctionDefinition{
-				Name:        llms.URLsName,
-				Description: llms.URLsDescription,
-				Parameters:  generateSchema(llms.UrlsResp{}),
-			},
-		},
-	}
-
-	return &gpt{
+func New(client *openai.Client, model string, imageSupport bool, opts ...Option) llms.Plugin {
+	g := &gpt{
 		client:       client,
 		imageSupport: imageSupport,
 		model:        model,
-		tools:        t,
-		toolChoice:   "required",
 	}
-}
+	for _, opt := range opts {
+		opt(g)
+	}
 
-func (g *gpt) AddPrompt(_ []byte, text, chatID, toolName string) {
-	role := openai.ChatMessageRoleUser
+	for _, tool := range llms.Tools.All() {
+		if g.strict && tool.Name() == llms.RoomsName {
+			g.roomsFormat = &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+				JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+					Name:        tool.Name(),
+					Description: tool.Description(),
+					Schema:      tool.Schema().OpenAIStrict(),
+					Strict:      true,
+				},
+			}
 
-	if g.guided {
-		g.toolChoice = functionChoice{
-			Type: "function",
-			Function: struct {
-				Name string `json:"name"`
-			}{
-				Name: llms.URLsName,
-			},
+			continue
 		}
+
+		var parameters any = tool.Schema().OpenAI()
+		if g.strict {
+			parameters = tool.Schema().OpenAIStrict()
+		}
+		g.tools = append(g.tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Strict:      g.strict,
+				Parameters:  parameters,
+			},
+		})
 	}
 
-	if len(g.messages) > 0 {
-		role = openai.ChatMessageRoleTool
-		g.toolChoice = "required"
-	} else {
-		g.messages = append(g.messages, openai.ChatCompletionMessage{
+	return g
+}
+
+// messages translates the conversation to the openai chat format.
+func (g *gpt) messages(conversation *llms.Conversation) []openai.ChatCompletionMessage {
+	messages := []openai.ChatCompletionMessage{
+		{
 			Role:    openai.ChatMessageRoleSystem,
 			Content: llms.SystemPrompt,
-		}, openai.ChatCompletionMessage{
+		},
+		{
 			Role:    openai.ChatMessageRoleSystem,
 			Content: "Only call one tool function at a time",
+		},
+	}
+	if g.strict {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: strictPrompt,
+		})
+	}
+
+	for _, turn := range conversation.Turns {
+		message := openai.ChatCompletionMessage{
+			Content: turn.Text,
+		}
+
+		switch turn.Role {
+		case llms.RoleSystem:
+			message.Role = openai.ChatMessageRoleSystem
+		case llms.RoleUser:
+			message.Role = openai.ChatMessageRoleUser
+			if len(turn.Images) > 0 {
+				message.Content = ""
+				message.MultiContent = imageParts(turn)
+			}
+		case llms.RoleAssistant:
+			message.Role = openai.ChatMessageRoleAssistant
+			for _, toolCall := range turn.ToolCalls {
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+					ID:   toolCall.ID,
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      toolCall.Name,
+						Arguments: toolCall.Arguments,
+					},
+				})
+			}
+		case llms.RoleTool:
+			message.Role = openai.ChatMessageRoleTool
+			message.ToolCallID = turn.ToolCallID
+			message.Name = turn.ToolName
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// imageParts returns the text and the images of the turn as content parts.
+func imageParts(turn llms.Turn) []openai.ChatMessagePart {
+	parts := []openai.ChatMessagePart{
+		{
+			Type: openai.ChatMessagePartTypeText,
+			Text: turn.Text,
+		},
+	}
+	for _, image := range turn.Images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    "data:image/webp;base64," + base64.StdEncoding.EncodeToString(image),
+				Detail: openai.ImageURLDetailAuto,
+			},
 		})
 	}
 
-	g.messages = append(g.messages, openai.ChatCompletionMessage{
-		Role:       role,
-		Content:    text,
-		ToolCallID: chatID,
-		Name:       toolName,
-	})
+	return parts
+}
+
+func (g *gpt) toolChoice(conversation *llms.Conversation) any {
+	name := ""
+	if g.roomToolOnly {
+		name = llms.RoomsName
+	} else if g.guided && !conversation.HasAssistant() {
+		name = llms.URLsName
+	}
+
+	if name == "" {
+		return "required"
+	}
+
+	return functionChoice{
+		Type: "function",
+		Function: struct {
+			Name string `json:"name"`
+		}{
+			Name: name,
+		},
+	}
 }
 
-func (g *gpt) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+// request returns the chat completion request of the conversation. In strict mode the llm answers
+// with the rooms instead of calling a tool, so tool calls are optional and, if only rooms are
+// requested, no tools are offered.
+func (g *gpt) request(conversation *llms.Conversation) openai.ChatCompletionRequest {
 	request := openai.ChatCompletionRequest{
-		Model:      g.model,
-		Messages:   g.messages,
-		Tools:      g.tools,
-		ToolChoice: g.toolChoice,
+		Model:    g.model,
+		Messages: g.messages(conversation),
+	}
+	if !g.strict {
+		request.Tools = g.tools
+		request.ToolChoice = g.toolChoice(conversation)
+
+		return request
+	}
+
+	request.ResponseFormat = g.roomsFormat
+	if g.roomToolOnly {
+		return request
+	}
+	request.Tools = g.tools
+	// strict schemas don't allow parallel tool calls
+	request.ParallelToolCalls = false
+	request.ToolChoice = "auto"
+	if g.guided && !conversation.HasAssistant() {
+		request.ToolChoice = g.toolChoice(conversation)
 	}
 
+	return request
+}
+
+func (g *gpt) ExecutePrompt(ctx context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+	request := g.request(conversation)
+
 	startTime := time.Now()
 	resp, err := g.client.CreateChatCompletion(
 		ctx,
@@ -113,30 +245,32 @@ func (g *gpt) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, t
 
 	totalTokens := resp.Usage.TotalTokens
 
-	g.messages = append(g.messages, resp.Choices[0].Message)
-
 	response := []llms.LlmResposeWithChatID{}
 	if len(resp.Choices) > 0 {
-		for _, toolCall := range resp.Choices[0].Message.ToolCalls {
-			var resp interface{} = &llms.RoomsResp{}
-			if toolCall.Function.Name == llms.URLsName {
-				resp = &llms.UrlsResp{}
-			}
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &resp)
-			if err != nil {
-				return nil, duration, totalTokens, fmt.Errorf("failed to unmarshal response: %w", err)
-			}
-
-			result := llms.LlmResposeWithChatID{
-				ChatID:   toolCall.ID,
-				ToolName: toolCall.Function.Name,
-			}
-			if urls, ok := resp.(*llms.UrlsResp); ok {
-				result.URLs = urls.URLs
-			} else if rooms, ok := resp.(*llms.RoomsResp); ok {
-				result.Rooms = rooms.Rooms
-			}
+		message := resp.Choices[0].Message
+		toolCalls := []llms.ToolCall{}
+		for _, toolCall := range message.ToolCalls {
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:        toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			})
+		}
+		content := message.Content
+		if g.strict && len(toolCalls) == 0 && content != "" {
+			// the rooms are recorded as call of the rooms tool, so the conversation looks the same in both modes
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:        resp.ID,
+				Name:      llms.RoomsName,
+				Arguments: content,
+			})
+			content = ""
+		}
+		conversation.AddAssistant(content, toolCalls...)
 
+		for _, toolCall := range toolCalls {
+			result, err := llms.Tools.Decode(toolCall)
+			result.Err = err
 			response = append(response, result)
 		}
 	}
@@ -145,11 +279,14 @@ func (g *gpt) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, t
 }
 
 func (g *gpt) ResetChat() {
-	g.toolChoice = "required"
-	g.messages = []openai.ChatCompletionMessage{}
+	g.roomToolOnly = false
 }
 
 func (g *gpt) ModelName() string {
+	if g.strict {
+		return g.model + StrictSuffix
+	}
+
 	return g.model
 }
 
@@ -157,17 +294,17 @@ func (g *gpt) ImageSupport() bool {
 	return g.imageSupport
 }
 
+func (g *gpt) ContextWindow() int {
+	if window, ok := contextWindows[g.model]; ok {
+		return window
+	}
+
+	return defaultContextWindow
+}
+
 func (g *gpt) RoomToolOnly() {
 	g.guided = false
-
-	g.toolChoice = functionChoice{
-		Type: "function",
-		Function: struct {
-			Name string `json:"name"`
-		}{
-			Name: llms.RoomsName,
-		},
-	}
+	g.roomToolOnly = true
 }
 
 func (g *gpt) Guided(mode bool) {
@@ -0,0 +1,98 @@
+package gpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestRequest(t *testing.T) {
+	conversation := llms.NewConversation()
+	conversation.AddUser("List all escape rooms.")
+
+	g := New(nil, openai.GPT4oMini, false).(*gpt)
+	request := g.request(conversation)
+	if g.ModelName() != openai.GPT4oMini || len(request.Tools) != 2 || request.ToolChoice != "required" || request.ResponseFormat != nil || request.ParallelToolCalls != nil {
+		t.Errorf("Unexpected request %+v", request)
+	}
+
+	strict := New(nil, openai.GPT4oMini, false, WithStrict()).(*gpt)
+	if strict.ModelName() != openai.GPT4oMini+StrictSuffix {
+		t.Errorf("Expected the strict mode in the model name, got %s", strict.ModelName())
+	}
+	request = strict.request(conversation)
+	if request.Model != openai.GPT4oMini {
+		t.Errorf("Expected the model %s to be requested, got %s", openai.GPT4oMini, request.Model)
+	}
+	if len(request.Tools) != 1 || request.Tools[0].Function.Name != llms.URLsName || !request.Tools[0].Function.Strict {
+		t.Errorf("Expected the strict more content tool only, got %+v", request.Tools)
+	}
+	if request.ToolChoice != "auto" || request.ParallelToolCalls != false {
+		t.Errorf("Expected optional tool calls one at a time, got %v and %v", request.ToolChoice, request.ParallelToolCalls)
+	}
+	format := request.ResponseFormat
+	if format == nil || format.Type != openai.ChatCompletionResponseFormatTypeJSONSchema || !format.JSONSchema.Strict || format.JSONSchema.Name != llms.RoomsName {
+		t.Errorf("Expected the strict rooms response format, got %+v", format)
+	}
+
+	strict.Guided(true)
+	if choice, ok := strict.request(conversation).ToolChoice.(functionChoice); !ok || choice.Function.Name != llms.URLsName {
+		t.Errorf("Expected the guided first request to call the more content tool, got %+v", choice)
+	}
+
+	strict.RoomToolOnly()
+	request = strict.request(conversation)
+	if request.Tools != nil || request.ToolChoice != nil || request.ResponseFormat == nil {
+		t.Errorf("Expected the rooms response format without tools, got %+v", request)
+	}
+}
+
+func TestExecutePromptStrict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Errorf("Error decoding request: %v", err)
+		}
+		if _, ok := request["response_format"]; !ok {
+			t.Errorf("Expected a response format, got %v", request)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			ID: "chatcmpl-1",
+			Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleAssistant,
+					Content: `{"rooms":[{"name":"Die Gruft","genre":"Horror","duration":60,"players_min":null}]}`,
+				},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("token")
+	config.BaseURL = server.URL
+	g := New(openai.NewClientWithConfig(config), openai.GPT4oMini, false, WithStrict())
+
+	conversation := llms.NewConversation()
+	conversation.AddUser("List all escape rooms.")
+	responses, _, _, err := g.ExecutePrompt(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Error executing prompt: %v", err)
+	}
+	if len(responses) != 1 || responses[0].Err != nil || len(responses[0].Rooms) != 1 || responses[0].Rooms[0].Duration != 60 {
+		t.Fatalf("Expected the answer to list the room, got %+v", responses)
+	}
+
+	// the answer is recorded as call of the rooms tool
+	last := conversation.Last()
+	if last.Text != "" || len(last.ToolCalls) != 1 || last.ToolCalls[0].Name != llms.RoomsName || last.ToolCalls[0].ID != responses[0].ChatID {
+		t.Errorf("Expected a rooms tool call, got %+v", last)
+	}
+}
@@ -2,7 +2,6 @@ package jamba
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
@@ -12,6 +11,14 @@ import (
 
 var _ = (llms.Plugin)(&jamba{})
 
+// contextWindows holds the context window sizes of the known models in tokens.
+var contextWindows = map[string]int{
+	"jamba-1.5-large": 256000,
+	"jamba-1.5-mini":  256000,
+}
+
+const defaultContextWindow = 256000
+
 type jamba struct {
 	client       *jambaClient.ChatService
 	req          jambaClient.ChatCompletionRequest
@@ -19,27 +26,26 @@ type jamba struct {
 	guided       bool
 }
 
+func tools() []jambaClient.Tool {
+	tools := []jambaClient.Tool{}
+	for _, tool := range llms.Tools.All() {
+		tools = append(tools, jambaClient.Tool{
+			Type: "function",
+			Function: jambaClient.Function{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.Schema().Map(),
+			},
+		})
+	}
+
+	return tools
+}
+
 func New(jambaClientClient *jambaClient.ChatService, modelName string, _ float32, imageSupport bool) llms.Plugin {
 	req := jambaClient.ChatCompletionRequest{
 		Model: modelName,
-		Tools: []jambaClient.Tool{
-			{
-				Type: "function",
-				Function: jambaClient.Function{
-					Name:        llms.RoomsName,
-					Description: llms.RoomsDescription,
-					Parameters:  generateSchemaMap(llms.RoomsResp{}),
-				},
-			},
-			{
-				Type: "function",
-				Function: jambaClient.Function{
-					Name:        llms.URLsName,
-					Description: llms.URLsDescription,
-					Parameters:  generateSchemaMap(llms.UrlsResp{}),
-				},
-			},
-		},
+		Tools: tools(),
 		ResponseFormat: &jambaClient.ResponseFormat{
 			Type: "json_object",
 		},
@@ -52,47 +58,71 @@ func New(jambaClientClient *jambaClient.ChatService, modelName string, _ float32
 	}
 }
 
-func (j *jamba) AddPrompt(_ []byte, text, toolCallID, _ string) {
-	if toolCallID == "" {
-		messages := []jambaClient.Message{
-			{
-				Role:    jambaClient.RoleSystem,
-				Content: llms.SystemPrompt,
-			},
-			{
-				Role:    jambaClient.RoleSystem,
-				Content: "If not doing a toolcall, respond in following format: {\"rooms\":[{\"name\":\"The Secret Lab\",\"description\":\"Enter the mysterious lab of a mad scientist. Can you uncover the secrets and escape before time runs out?\",\"players_min\":2,\"players_max\":6,\"duration\":60,\"booking_url\":\"https://example.com/book/secret-lab\",\"detail_page_url\":\"https://example.com/rooms/secret-lab\",\"image_url\":\"https://example.com/images/secret-lab.jpg\",\"genre\":\"Science Fiction\",\"difficulty\":\"Medium\"},{\"name\":\"Pharaoh's Tomb\",\"description\":\"Trapped inside the tomb of an ancient Pharaoh, you must solve the riddles and find the way out before you are sealed inside forever.\",\"players_min\":4,\"players_max\":8,\"duration\":90,\"booking_url\":\"https://example.com/book/pharaohs-tomb\",\"detail_page_url\":\"https://example.com/rooms/pharaohs-tomb\",\"image_url\":\"https://example.com/images/pharaohs-tomb.jpg\",\"genre\":\"Egypt\",\"difficulty\":\"Hard\"},{\"name\":\"Haunted Mansion\",\"description\":\"A ghostly adventure awaits inside this eerie mansion. Can you solve the mystery of the haunted estate and escape its grasp?\",\"players_min\":3,\"players_max\":5,\"duration\":75,\"booking_url\":\"https://example.com/book/haunted-mansion\",\"detail_page_url\":\"https://example.com/rooms/haunted-mansion\",\"image_url\":\"https://example.com/images/haunted-mansion.jpg\",\"genre\":\"Horror\",\"difficulty\":\"Easy\"}]}",
-			},
-			{
-				Role:    jambaClient.RoleSystem,
-				Content: "Do not request a url you already have access to.",
-			},
-		}
-		if j.guided {
-			messages = append(messages, jambaClient.Message{
-				Role:    jambaClient.RoleSystem,
-				Content: fmt.Sprintf("Use the %s tool/function call in your first response", llms.URLsName),
-			})
-		}
-		j.req.Messages = append(j.req.Messages, messages...)
+// messages translates the conversation to the jamba chat format.
+func (j *jamba) messages(conversation *llms.Conversation) []jambaClient.Message {
+	messages := []jambaClient.Message{
+		{
+			Role:    jambaClient.RoleSystem,
+			Content: llms.SystemPrompt,
+		},
+		{
+			Role:    jambaClient.RoleSystem,
+			Content: "If not doing a toolcall, respond in following format: {\"rooms\":[{\"name\":\"The Secret Lab\",\"description\":\"Enter the mysterious lab of a mad scientist. Can you uncover the secrets and escape before time runs out?\",\"players_min\":2,\"players_max\":6,\"duration\":60,\"booking_url\":\"https://example.com/book/secret-lab\",\"detail_page_url\":\"https://example.com/rooms/secret-lab\",\"image_url\":\"https://example.com/images/secret-lab.jpg\",\"genre\":\"Science Fiction\",\"difficulty\":\"Medium\"},{\"name\":\"Pharaoh's Tomb\",\"description\":\"Trapped inside the tomb of an ancient Pharaoh, you must solve the riddles and find the way out before you are sealed inside forever.\",\"players_min\":4,\"players_max\":8,\"duration\":90,\"booking_url\":\"https://example.com/book/pharaohs-tomb\",\"detail_page_url\":\"https://example.com/rooms/pharaohs-tomb\",\"image_url\":\"https://example.com/images/pharaohs-tomb.jpg\",\"genre\":\"Egypt\",\"difficulty\":\"Hard\"},{\"name\":\"Haunted Mansion\",\"description\":\"A ghostly adventure awaits inside this eerie mansion. Can you solve the mystery of the haunted estate and escape its grasp?\",\"players_min\":3,\"players_max\":5,\"duration\":75,\"booking_url\":\"https://example.com/book/haunted-mansion\",\"detail_page_url\":\"https://example.com/rooms/haunted-mansion\",\"image_url\":\"https://example.com/images/haunted-mansion.jpg\",\"genre\":\"Horror\",\"difficulty\":\"Easy\"}]}",
+		},
+		{
+			Role:    jambaClient.RoleSystem,
+			Content: "Do not request a url you already have access to.",
+		},
 	}
-
-	message := jambaClient.Message{
-		Role:    jambaClient.RoleUser,
-		Content: text,
+	if j.guided {
+		messages = append(messages, jambaClient.Message{
+			Role:    jambaClient.RoleSystem,
+			Content: fmt.Sprintf("Use the %s tool/function call in your first response", llms.URLsName),
+		})
 	}
 
-	if toolCallID != "" {
-		message.Role = jambaClient.RoleTool
-		message.ToolCallID = toolCallID
+	for _, turn := range conversation.Turns {
+		message := jambaClient.Message{
+			Content: turn.Text,
+		}
+
+		switch turn.Role {
+		case llms.RoleSystem:
+			message.Role = jambaClient.RoleSystem
+		case llms.RoleUser:
+			message.Role = jambaClient.RoleUser
+		case llms.RoleAssistant:
+			message.Role = jambaClient.RoleAssistant
+			if message.Content == "" {
+				message.Content = "No content provided by the assistant"
+			}
+			for _, toolCall := range turn.ToolCalls {
+				message.ToolCalls = append(message.ToolCalls, jambaClient.ToolCall{
+					ID:   toolCall.ID,
+					Type: string(jambaClient.ToolCallTypeFunction),
+					Function: jambaClient.FunctionResponse{
+						Name:      toolCall.Name,
+						Arguments: toolCall.Arguments,
+					},
+				})
+			}
+		case llms.RoleTool:
+			message.Role = jambaClient.RoleTool
+			message.ToolCallID = turn.ToolCallID
+		}
+
+		messages = append(messages, message)
 	}
 
-	j.req.Messages = append(j.req.Messages, message)
+	return messages
 }
 
-func (j *jamba) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+func (j *jamba) ExecutePrompt(ctx context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+	req := j.req
+	req.Messages = j.messages(conversation)
+
 	startTime := time.Now()
-	resp, err := j.client.CreateChatCompletion(ctx, j.req)
+	resp, err := j.client.CreateChatCompletion(ctx, req)
 	duration := time.Since(startTime)
 	if err != nil {
 		return nil, duration, 0, fmt.Errorf("GenerateContent error: %w", err)
@@ -104,52 +134,33 @@ func (j *jamba) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID,
 	}
 
 	responses := []llms.LlmResposeWithChatID{}
-	for _, choice := range resp.Choices {
-		message := jambaClient.Message{
-			Role:      jambaClient.RoleAssistant,
-			Content:   "No content provided by the assistant",
-			ToolCalls: choice.Message.ToolCalls,
-		}
+	for i, choice := range resp.Choices {
+		content := ""
 		if choice.Message.Content != nil {
-			message.Content = *choice.Message.Content
+			content = *choice.Message.Content
 		}
-		j.req.Messages = append(j.req.Messages, message)
-		if len(choice.Message.ToolCalls) == 0 && choice.Message.Content != nil {
-			llmResponse := llms.RoomsResp{}
-			err = json.Unmarshal([]byte(*choice.Message.Content), &llmResponse)
-			if err != nil {
-				return nil, duration, totalTokens, fmt.Errorf("failed to unmarshal response: %w", err)
-			}
-
-			responses = append(responses, llms.LlmResposeWithChatID{
-				RoomsResp: llmResponse,
+		toolCalls := []llms.ToolCall{}
+		for _, toolCall := range choice.Message.ToolCalls {
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:        toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
 			})
-
-			continue
 		}
+		if len(toolCalls) == 0 && content != "" {
+			// rooms answered in the content are recorded as call of the rooms tool, so they are repaired and answered like one
+			toolCalls = append(toolCalls, llms.ToolCall{
+				ID:        fmt.Sprintf("%s-%d", resp.ID, i),
+				Name:      llms.RoomsName,
+				Arguments: content,
+			})
+			content = ""
+		}
+		conversation.AddAssistant(content, toolCalls...)
 
-		for _, toolCall := range choice.Message.ToolCalls {
-			var llmResponse interface{} = &llms.RoomsResp{}
-			if toolCall.Function.Name == llms.URLsName {
-				llmResponse = &llms.UrlsResp{}
-			}
-
-			err = json.Unmarshal([]byte(toolCall.Function.Arguments), &llmResponse)
-			if err != nil {
-				return nil, duration, totalTokens, fmt.Errorf("failed to unmarshal response: %w", err)
-			}
-
-			result := llms.LlmResposeWithChatID{
-				ChatID:   toolCall.ID,
-				ToolName: toolCall.Function.Name,
-			}
-
-			if urls, ok := llmResponse.(*llms.UrlsResp); ok {
-				result.URLs = urls.URLs
-			} else if rooms, ok := llmResponse.(*llms.RoomsResp); ok {
-				result.Rooms = rooms.Rooms
-			}
-
+		for _, toolCall := range toolCalls {
+			result, err := llms.Tools.Decode(toolCall)
+			result.Err = err
 			responses = append(responses, result)
 		}
 	}
@@ -165,8 +176,15 @@ func (j *jamba) ImageSupport() bool {
 	return j.imageSupport
 }
 
+func (j *jamba) ContextWindow() int {
+	if window, ok := contextWindows[j.req.Model]; ok {
+		return window
+	}
+
+	return defaultContextWindow
+}
+
 func (j *jamba) ResetChat() {
-	j.req.Messages = nil
 }
 
 func (j *jamba) Guided(mode bool) {
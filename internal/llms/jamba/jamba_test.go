@@ -0,0 +1,62 @@
+package jamba_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/jamba"
+	"github.com/martinbockt/esc-llm-webscraper/pkg/jambaClient"
+	"go.uber.org/zap"
+)
+
+func TestExecutePromptContent(t *testing.T) {
+	content := `{"rooms":[{"name":"Die Gruft","genre":"Horror","duration":"60 Min.","players_min":"2-6"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jambaClient.ChatCompletionResponse{
+			ID: "chat-1",
+			Choices: []jambaClient.Choice{{
+				Message: jambaClient.ResponseMessage{
+					Role:    string(jambaClient.RoleAssistant),
+					Content: &content,
+				},
+			}},
+			Usage: &jambaClient.Usage{TotalTokens: 1200},
+		})
+	}))
+	defer server.Close()
+
+	client := jambaClient.NewChatService(zap.NewNop(), "token", jambaClient.WithBaseURL(server.URL))
+	j := jamba.New(client, "jamba-1.5-mini", 0, false)
+
+	conversation := llms.NewConversation()
+	conversation.AddUser("List all escape rooms.")
+	responses, _, tokens, err := j.ExecutePrompt(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Error executing prompt: %v", err)
+	}
+	if tokens != 1200 {
+		t.Errorf("Expected 1200 tokens, got %d", tokens)
+	}
+
+	// the answer is repaired like a call of the rooms tool
+	if len(responses) != 1 || len(responses[0].Rooms) != 1 {
+		t.Fatalf("Expected the answer to list one room, got %+v", responses)
+	}
+	response := responses[0]
+	if response.Rooms[0].Duration != 60 || response.Rooms[0].PlayersMin != 2 {
+		t.Errorf("Expected the repaired room, got %+v", response.Rooms[0])
+	}
+	if response.ToolName != llms.RoomsName || response.ChatID == "" {
+		t.Errorf("Expected the response of the rooms tool, got %+v", response)
+	}
+
+	last := conversation.Last()
+	if last.Text != "" || len(last.ToolCalls) != 1 || last.ToolCalls[0].Name != llms.RoomsName || last.ToolCalls[0].ID != response.ChatID {
+		t.Errorf("Expected a rooms tool call, got %+v", last)
+	}
+}
@@ -6,6 +6,7 @@ package llama
 // 	"time"
 
 // 	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+// 	"github.com/martinbockt/esc-llm-webscraper/internal/llms/schema"
 // 	"github.com/martinbockt/esc-llm-webscraper/pkg/togetherai"
 // )
 
@@ -29,7 +30,7 @@ package llama
 // 				Function: togetherai.Function{
 // 					Name:        llms.RoomsName,
 // 					Description: llms.RoomsDescription,
-// 					Parameters:  generateSchemaMap(llms.RoomsResp{}),
+// 					Parameters:  schema.Generate(llms.RoomsResp{}).Map(),
 // 				},
 // 			},
 // 			{
@@ -37,7 +38,7 @@ package llama
 // 				Function: togetherai.Function{
 // 					Name:        llms.URLsName,
 // 					Description: llms.URLsDescription,
-// 					Parameters:  generateSchemaMap(llms.UrlsResp{}),
+// 					Parameters:  schema.Generate(llms.UrlsResp{}).Map(),
 // 				},
 // 			},
 // 		},
@@ -17,6 +17,8 @@ type LlmResposeWithChatID struct {
 	ChatID     string
 	ToolName   string
 	TokenUsage int
+	// Err is the ToolError of a failed tool call, it is sent back to the llm as result of the call.
+	Err error
 	UrlsResp
 	RoomsResp
 }
@@ -28,7 +30,7 @@ type LlmResponse struct {
 }
 
 type UrlsResp struct {
-	URLs []string `json:"urls" description:"Array of URLs to scrape (most relevant are escape room detail pages). The URLs must be valid URLs."`
+	URLs []string `json:"urls" description:"Array of URLs to scrape (most relevant are escape room detail pages). The URLs must be valid URLs." format:"uri"`
 }
 
 type RoomsResp struct {
@@ -38,12 +40,12 @@ type RoomsResp struct {
 type Room struct {
 	Name          string `json:"name"            description:"Name of the escape room"`
 	Description   string `json:"description"     description:"Description of the escape room. You find it on the detail page of the escape room."`
-	PlayersMin    int    `json:"players_min"     description:"Minimum number of players"`
-	PlayersMax    int    `json:"players_max"     description:"Maximum number of players"`
-	Duration      int    `json:"duration"        description:"Duration of the escape room in minutes"`
-	BookingURL    string `json:"booking_url"     description:"The full URL/a link to book the escape room."`
-	DetailPageURL string `json:"detail_page_url" description:"The full URL/a link to the detail page of the escape room"`
-	ImageURL      string `json:"image_url"       description:"The full URL/a link to a room related image. Most of the time on top of the detail page of an escape room."`
+	PlayersMin    int    `json:"players_min"     description:"Minimum number of players"                                                                                  minimum:"0" maximum:"100"`
+	PlayersMax    int    `json:"players_max"     description:"Maximum number of players"                                                                                  minimum:"0" maximum:"100" range:"upper"`
+	Duration      int    `json:"duration"        description:"Duration of the escape room in minutes"                                                                     minimum:"0" maximum:"600"`
+	BookingURL    string `json:"booking_url"     description:"The full URL/a link to book the escape room."                                                               format:"uri"`
+	DetailPageURL string `json:"detail_page_url" description:"The full URL/a link to the detail page of the escape room"                                                  format:"uri"`
+	ImageURL      string `json:"image_url"       description:"The full URL/a link to a room related image. Most of the time on top of the detail page of an escape room." format:"uri"`
 	Genre         string `json:"genre"           description:"Select the genre/enum value that most closely matches the escape room."                                     enum:"Adventure,Crime,Egypt,Fantasy,Historical,Horror,Medieval,Prison,Science Fiction,Steampunk,Western" required:"true"`
 	Difficulty    string `json:"difficulty"      description:"Difficulty of the escape room"`
 }
@@ -56,11 +58,14 @@ const (
 	RoomsDescription = "List all available escape rooms of the website. With this you are ending the conversation."
 )
 
+// Plugin translates a Conversation to the wire format of a provider. ExecutePrompt
+// appends the answer of the llm as assistant turn to the conversation.
 type Plugin interface {
 	ModelName() string
-	AddPrompt(image []byte, text, chatID, toolName string)
-	ExecutePrompt(ctx context.Context) ([]LlmResposeWithChatID, time.Duration, int, error)
+	ExecutePrompt(ctx context.Context, conversation *Conversation) ([]LlmResposeWithChatID, time.Duration, int, error)
 	ImageSupport() bool
+	// ContextWindow is the maximum number of tokens the model accepts per request.
+	ContextWindow() int
 	ResetChat()
 	Guided(mode bool)
 	RoomToolOnly()
@@ -0,0 +1,184 @@
+// Package llmstest provides a scripted llms.Plugin for tests.
+package llmstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+// ErrScriptExhausted is returned by ExecutePrompt when all steps of the script are used.
+var ErrScriptExhausted = errors.New("script exhausted")
+
+// Step is a scripted answer of the Plugin. URLs and Rooms become calls of the
+// more content and the list rooms tool, an Err fails the request.
+type Step struct {
+	Text  string
+	URLs  []string
+	Rooms []llms.Room
+	// Call is a raw tool call, decoded like the plugins do, e.g. to call an unknown tool.
+	Call     *llms.ToolCall
+	Tokens   int
+	Duration time.Duration
+	Err      error
+}
+
+// Plugin answers the prompts with the steps of its script, in order.
+type Plugin struct {
+	Name   string
+	Window int
+	Script []Step
+	// Images is reported as image support.
+	Images bool
+
+	mu            sync.Mutex
+	step          int
+	conversations []*llms.Conversation
+	guided        bool
+	roomToolOnly  bool
+
+	roomToolOnlyPrompts int
+}
+
+// New creates a plugin of the model answering with the steps.
+func New(name string, steps ...Step) *Plugin {
+	return &Plugin{
+		Name:   name,
+		Window: 128000,
+		Script: steps,
+	}
+}
+
+func (p *Plugin) ModelName() string {
+	return p.Name
+}
+
+// ExecutePrompt records a copy of the conversation and answers with the next step of the script.
+func (p *Plugin) ExecutePrompt(_ context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.conversations = append(p.conversations, conversation.Clone())
+	if p.roomToolOnly {
+		p.roomToolOnlyPrompts++
+	}
+	if p.step >= len(p.Script) {
+		return nil, 0, 0, fmt.Errorf("%w after %d steps", ErrScriptExhausted, p.step)
+	}
+
+	step := p.Script[p.step]
+	p.step++
+	if step.Err != nil {
+		return nil, step.Duration, step.Tokens, step.Err
+	}
+
+	toolCalls := []llms.ToolCall{}
+	response := []llms.LlmResposeWithChatID{}
+	if step.URLs != nil && !p.roomToolOnly {
+		call, err := toolCall(conversation, llms.URLsName, llms.UrlsResp{URLs: step.URLs})
+		if err != nil {
+			return nil, step.Duration, step.Tokens, err
+		}
+		toolCalls = append(toolCalls, call)
+		response = append(response, llms.LlmResposeWithChatID{
+			ChatID:   call.ID,
+			ToolName: call.Name,
+			UrlsResp: llms.UrlsResp{URLs: step.URLs},
+		})
+	}
+	if step.Rooms != nil {
+		call, err := toolCall(conversation, llms.RoomsName, llms.RoomsResp{Rooms: step.Rooms})
+		if err != nil {
+			return nil, step.Duration, step.Tokens, err
+		}
+		toolCalls = append(toolCalls, call)
+		response = append(response, llms.LlmResposeWithChatID{
+			ChatID:    call.ID,
+			ToolName:  call.Name,
+			RoomsResp: llms.RoomsResp{Rooms: step.Rooms},
+		})
+	}
+	if step.Call != nil {
+		call := *step.Call
+		call.ID = fmt.Sprintf("%s-%d", call.Name, conversation.Len())
+		toolCalls = append(toolCalls, call)
+		resp, err := llms.Tools.Decode(call)
+		resp.Err = err
+		response = append(response, resp)
+	}
+	conversation.AddAssistant(step.Text, toolCalls...)
+
+	return response, step.Duration, step.Tokens, nil
+}
+
+func toolCall(conversation *llms.Conversation, name string, args any) (llms.ToolCall, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return llms.ToolCall{}, fmt.Errorf("failed to marshal tool arguments: %w", err)
+	}
+
+	return llms.ToolCall{
+		ID:        fmt.Sprintf("%s-%d", name, conversation.Len()),
+		Name:      name,
+		Arguments: string(data),
+	}, nil
+}
+
+func (p *Plugin) ImageSupport() bool {
+	return p.Images
+}
+
+func (p *Plugin) ContextWindow() int {
+	return p.Window
+}
+
+func (p *Plugin) ResetChat() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.roomToolOnly = false
+}
+
+func (p *Plugin) Guided(mode bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.guided = mode
+}
+
+func (p *Plugin) RoomToolOnly() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.guided = false
+	p.roomToolOnly = true
+}
+
+// Conversations returns copies of the conversations of all prompts.
+func (p *Plugin) Conversations() []*llms.Conversation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*llms.Conversation(nil), p.conversations...)
+}
+
+// Steps returns the number of used steps.
+func (p *Plugin) Steps() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.step
+}
+
+// RoomToolOnlyPrompts returns the number of prompts answered while only the rooms tool was offered.
+func (p *Plugin) RoomToolOnlyPrompts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.roomToolOnlyPrompts
+}
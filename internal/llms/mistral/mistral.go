@@ -2,7 +2,6 @@ package mistral
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -15,114 +14,118 @@ import (
 
 var _ = (llms.Plugin)(&mistral{})
 
+// contextWindows holds the context window sizes of the known models in tokens.
+var contextWindows = map[string]int{
+	"mistral-large-2407": 128000,
+}
+
+const defaultContextWindow = 32000
+
 type mistral struct {
 	imageSupport bool
-	tools        []langchain.Tool
 	model        *mistralSDK.Model
-	messages     []langchain.MessageContent
 	modelName    string
+	roomToolOnly bool
 	guided       bool
 }
 
-func New(model string, token string, imageSupport bool) llms.Plugin {
-	llm, err := mistralSDK.New(mistralSDK.WithModel(model), mistralSDK.WithAPIKey(token))
+// New creates the plugin of the model. The options are passed to the mistral client, e.g. to set the endpoint.
+func New(model string, token string, imageSupport bool, opts ...mistralSDK.Option) llms.Plugin {
+	llm, err := mistralSDK.New(append([]mistralSDK.Option{mistralSDK.WithModel(model), mistralSDK.WithAPIKey(token)}, opts...)...)
 	if err != nil {
 		panic(fmt.Errorf("failed to create LLM: %w", err))
 	}
 
-	tools := []langchain.Tool{
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.RoomsName,
-				Description: llms.RoomsDescription,
-				Parameters:  generateSchemaMap(llms.RoomsResp{}),
-			},
-		},
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.URLsName,
-				Description: llms.URLsDescription,
-				Parameters:  generateSchemaMap(llms.UrlsResp{}),
-			},
-		},
-	}
-
 	return &mistral{
-		tools:        tools,
 		imageSupport: imageSupport,
 		model:        llm,
 		modelName:    model,
 	}
 }
 
-func (m *mistral) AddPrompt(image []byte, text, chatID, toolName string) {
-	content := []langchain.ContentPart{
-		langchain.TextPart(llms.SystemPrompt),
-		langchain.TextPart(text),
+func langchainTool(tool llms.Tool) langchain.Tool {
+	return langchain.Tool{
+		Type: "function",
+		Function: &langchain.FunctionDefinition{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Schema().Map(),
+		},
 	}
+}
 
-	if m.guided {
-		m.tools = []langchain.Tool{
-			{
-				Type: "function",
-				Function: &langchain.FunctionDefinition{
-					Name:        llms.URLsName,
-					Description: llms.URLsDescription,
-					Parameters:  generateSchemaMap(llms.UrlsResp{}),
-				},
-			},
-		}
+func (m *mistral) tools(conversation *llms.Conversation) []langchain.Tool {
+	if m.roomToolOnly {
+		return []langchain.Tool{langchainTool(llms.RoomsTool)}
 	}
 
-	if chatID != "" {
-		m.tools = []langchain.Tool{
-			{
-				Type: "function",
-				Function: &langchain.FunctionDefinition{
-					Name:        llms.URLsName,
-					Description: llms.URLsDescription,
-					Parameters:  generateSchemaMap(llms.UrlsResp{}),
-				},
-			},
-			{
-				Type: "function",
-				Function: &langchain.FunctionDefinition{
-					Name:        llms.RoomsName,
-					Description: llms.RoomsDescription,
-					Parameters:  generateSchemaMap(llms.RoomsResp{}),
-				},
-			},
-		}
-
-		content = []langchain.ContentPart{
-			langchain.ToolCallResponse{
-				ToolCallID: chatID,
-				Name:       toolName,
-				Content:    text,
-			},
-		}
+	if m.guided && !conversation.HasAssistant() {
+		return []langchain.Tool{langchainTool(llms.URLsTool)}
 	}
 
-	if len(image) > 0 {
-		content = append(content, langchain.BinaryPart("image/webp", image))
-	}
+	return []langchain.Tool{langchainTool(llms.URLsTool), langchainTool(llms.RoomsTool)}
+}
 
-	role := langchain.ChatMessageTypeHuman
-	if len(m.messages) > 0 {
-		role = langchain.ChatMessageTypeTool
+// messages translates the conversation to the langchain message format.
+func (m *mistral) messages(conversation *llms.Conversation) []langchain.MessageContent {
+	messages := []langchain.MessageContent{}
+	systemPrompted := false
+	for _, turn := range conversation.Turns {
+		switch turn.Role {
+		case llms.RoleSystem:
+			messages = append(messages, langchain.TextParts(langchain.ChatMessageTypeSystem, turn.Text))
+		case llms.RoleUser:
+			content := []langchain.ContentPart{
+				langchain.TextPart(turn.Text),
+			}
+			if !systemPrompted {
+				content = append([]langchain.ContentPart{langchain.TextPart(llms.SystemPrompt)}, content...)
+				systemPrompted = true
+			}
+			for _, image := range turn.Images {
+				content = append(content, langchain.BinaryPart("image/webp", image))
+			}
+
+			messages = append(messages, langchain.MessageContent{
+				Role:  langchain.ChatMessageTypeHuman,
+				Parts: content,
+			})
+		case llms.RoleAssistant:
+			for _, toolCall := range turn.ToolCalls {
+				messages = append(messages, langchain.MessageContent{
+					Role: langchain.ChatMessageTypeAI,
+					Parts: []langchain.ContentPart{
+						langchain.ToolCall{
+							ID:   toolCall.ID,
+							Type: "function",
+							FunctionCall: &langchain.FunctionCall{
+								Name:      toolCall.Name,
+								Arguments: toolCall.Arguments,
+							},
+						},
+					},
+				})
+			}
+		case llms.RoleTool:
+			messages = append(messages, langchain.MessageContent{
+				Role: langchain.ChatMessageTypeTool,
+				Parts: []langchain.ContentPart{
+					langchain.ToolCallResponse{
+						ToolCallID: turn.ToolCallID,
+						Name:       turn.ToolName,
+						Content:    turn.Text,
+					},
+				},
+			})
+		}
 	}
 
-	m.messages = append(m.messages, langchain.MessageContent{
-		Role:  role,
-		Parts: content,
-	})
+	return messages
 }
 
-func (m *mistral) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+func (m *mistral) ExecutePrompt(ctx context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
 	startTime := time.Now()
-	resp, err := m.model.GenerateContent(ctx, m.messages, langchain.WithTools(m.tools), langchain.WithToolChoice("any"), langchain.WithMaxTokens(40000))
+	resp, err := m.model.GenerateContent(ctx, m.messages(conversation), langchain.WithTools(m.tools(conversation)), langchain.WithToolChoice("any"), langchain.WithMaxTokens(40000))
 	duration := time.Since(startTime)
 	if err != nil {
 		return nil, duration, 0, err
@@ -134,46 +137,22 @@ func (m *mistral) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatI
 
 	llmResponseWithChatID := []llms.LlmResposeWithChatID{}
 
+	toolCalls := []llms.ToolCall{}
 	for _, choice := range resp.Choices {
 		for _, toolCall := range choice.ToolCalls {
-			assistantResponse := langchain.MessageContent{
-				Role: langchain.ChatMessageTypeAI,
-				Parts: []langchain.ContentPart{
-					langchain.ToolCall{
-						ID:   toolCall.ID,
-						Type: toolCall.Type,
-						FunctionCall: &langchain.FunctionCall{
-							Name:      toolCall.FunctionCall.Name,
-							Arguments: toolCall.FunctionCall.Arguments,
-						},
-					},
-				},
-			}
-			m.messages = append(m.messages, assistantResponse)
-			var llmResponse interface{} = &llms.RoomsResp{}
-			if toolCall.FunctionCall.Name == llms.URLsName {
-				llmResponse = &llms.UrlsResp{}
-			}
-
-			err = json.Unmarshal([]byte(toolCall.FunctionCall.Arguments), &llmResponse)
-			if err != nil {
-				return nil, duration, 0, fmt.Errorf("failed to unmarshal response: %w", err)
-			}
-
-			result := llms.LlmResposeWithChatID{
-				ToolName: toolCall.FunctionCall.Name,
-				ChatID:   toolCall.ID,
-			}
-
-			if urls, ok := llmResponse.(*llms.UrlsResp); ok {
-				result.URLs = urls.URLs
-			} else if rooms, ok := llmResponse.(*llms.RoomsResp); ok {
-				result.Rooms = rooms.Rooms
+			call := llms.ToolCall{
+				ID:        toolCall.ID,
+				Name:      toolCall.FunctionCall.Name,
+				Arguments: toolCall.FunctionCall.Arguments,
 			}
+			toolCalls = append(toolCalls, call)
 
+			result, err := llms.Tools.Decode(call)
+			result.Err = err
 			llmResponseWithChatID = append(llmResponseWithChatID, result)
 		}
 	}
+	conversation.AddAssistant(resp.Choices[0].Content, toolCalls...)
 
 	return llmResponseWithChatID, duration, 0, nil
 }
@@ -186,27 +165,16 @@ func (m *mistral) ImageSupport() bool {
 	return m.imageSupport
 }
 
-func (m *mistral) ResetChat() {
-	m.tools = []langchain.Tool{
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.URLsName,
-				Description: llms.URLsDescription,
-				Parameters:  generateSchemaMap(llms.UrlsResp{}),
-			},
-		},
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.RoomsName,
-				Description: llms.RoomsDescription,
-				Parameters:  generateSchemaMap(llms.RoomsResp{}),
-			},
-		},
+func (m *mistral) ContextWindow() int {
+	if window, ok := contextWindows[m.modelName]; ok {
+		return window
 	}
 
-	m.messages = nil
+	return defaultContextWindow
+}
+
+func (m *mistral) ResetChat() {
+	m.roomToolOnly = false
 }
 
 func (m *mistral) Guided(mode bool) {
@@ -215,14 +183,5 @@ func (m *mistral) Guided(mode bool) {
 
 func (m *mistral) RoomToolOnly() {
 	m.guided = false
-	m.tools = []langchain.Tool{
-		{
-			Type: "function",
-			Function: &langchain.FunctionDefinition{
-				Name:        llms.RoomsName,
-				Description: llms.RoomsDescription,
-				Parameters:  generateSchemaMap(llms.RoomsResp{}),
-			},
-		},
-	}
+	m.roomToolOnly = true
 }
@@ -0,0 +1,73 @@
+package llms
+
+import (
+	"github.com/martinbockt/esc-llm-webscraper/internal/identity"
+)
+
+// MergeRooms merges the partial records of the same room, e.g. extracted from
+// different chunks of a page. Rooms are the same if identity.Same reports so.
+// Empty fields are filled by later records.
+func MergeRooms(rooms []Room) []Room {
+	merged := []Room{}
+	for _, room := range rooms {
+		index := -1
+		for i, m := range merged {
+			if identity.Same(RoomIdentity(m), RoomIdentity(room)) {
+				index = i
+
+				break
+			}
+		}
+
+		if index == -1 {
+			merged = append(merged, room)
+
+			continue
+		}
+
+		merged[index] = mergeRoom(merged[index], room)
+	}
+
+	return merged
+}
+
+// RoomIdentity returns the fields identifying the room.
+func RoomIdentity(room Room) identity.Room {
+	return identity.Room{
+		Name:          room.Name,
+		DetailPageURL: room.DetailPageURL,
+	}
+}
+
+func mergeRoom(a, b Room) Room {
+	a.Name = firstString(a.Name, b.Name)
+	if len(b.Description) > len(a.Description) {
+		a.Description = b.Description
+	}
+	a.PlayersMin = firstInt(a.PlayersMin, b.PlayersMin)
+	a.PlayersMax = firstInt(a.PlayersMax, b.PlayersMax)
+	a.Duration = firstInt(a.Duration, b.Duration)
+	a.BookingURL = firstString(a.BookingURL, b.BookingURL)
+	a.DetailPageURL = firstString(a.DetailPageURL, b.DetailPageURL)
+	a.ImageURL = firstString(a.ImageURL, b.ImageURL)
+	a.Genre = firstString(a.Genre, b.Genre)
+	a.Difficulty = firstString(a.Difficulty, b.Difficulty)
+
+	return a
+}
+
+func firstString(a, b string) string {
+	if a != "" {
+		return a
+	}
+
+	return b
+}
+
+func firstInt(a, b int) int {
+	if a != 0 {
+		return a
+	}
+
+	return b
+}
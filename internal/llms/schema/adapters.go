@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// genaiFormats are the string formats Gemini accepts, other formats are described instead.
+var genaiFormats = map[string]bool{
+	"date-time": true,
+	"enum":      true,
+}
+
+// OpenAI returns the schema as definition of the OpenAI client. The definition has no bounds
+// and formats, so they are added to the description.
+func (s *Schema) OpenAI() *jsonschema.Definition {
+	definition := &jsonschema.Definition{
+		Type:        jsonschema.DataType(s.Type),
+		Description: s.describe(true, true),
+		Enum:        s.Enum,
+		Required:    s.Required,
+	}
+	if s.Items != nil {
+		definition.Items = s.Items.OpenAI()
+	}
+	if s.Type == Object && s.Properties != nil {
+		definition.Properties = make(map[string]jsonschema.Definition, len(s.Properties))
+		for name, property := range s.Properties {
+			definition.Properties[name] = *property.OpenAI()
+		}
+	}
+
+	return definition
+}
+
+// StrictDefinition is the schema in the form the strict mode of OpenAI structured outputs accepts.
+type StrictDefinition map[string]any
+
+func (d StrictDefinition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(d))
+}
+
+// OpenAIStrict returns the schema for the strict mode of OpenAI structured outputs. It needs every
+// property to be required and forbids additional properties, so optional properties are nullable
+// instead. Bounds and formats are added to the description like for OpenAI.
+func (s *Schema) OpenAIStrict() StrictDefinition {
+	return s.strict(false)
+}
+
+func (s *Schema) strict(nullable bool) StrictDefinition {
+	definition := StrictDefinition{
+		"type": string(s.Type),
+	}
+	if nullable {
+		definition["type"] = []string{string(s.Type), "null"}
+	}
+	if description := s.describe(true, true); description != "" {
+		definition["description"] = description
+	}
+	if len(s.Enum) > 0 {
+		enum := []any{}
+		for _, value := range s.Enum {
+			enum = append(enum, value)
+		}
+		if nullable {
+			enum = append(enum, nil)
+		}
+		definition["enum"] = enum
+	}
+	if s.Items != nil {
+		definition["items"] = s.Items.strict(false)
+	}
+	if s.Type == Object {
+		properties := map[string]any{}
+		required := make([]string, 0, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.strict(!slices.Contains(s.Required, name))
+			required = append(required, name)
+		}
+		slices.Sort(required)
+		definition["properties"] = properties
+		definition["required"] = required
+		definition["additionalProperties"] = false
+	}
+
+	return definition
+}
+
+// Genai returns the schema as schema of the Vertex AI client.
+func (s *Schema) Genai() *genai.Schema {
+	format := s.Format
+	if !genaiFormats[format] {
+		format = ""
+	}
+
+	schema := &genai.Schema{
+		Type:        genaiType(s.Type),
+		Description: s.describe(false, format == ""),
+		Enum:        s.Enum,
+		Format:      format,
+		Required:    s.Required,
+	}
+	if s.Minimum != nil {
+		schema.Minimum = *s.Minimum
+	}
+	if s.Maximum != nil {
+		schema.Maximum = *s.Maximum
+	}
+	if s.Items != nil {
+		schema.Items = s.Items.Genai()
+	}
+	if s.Type == Object && s.Properties != nil {
+		schema.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			schema.Properties[name] = property.Genai()
+		}
+	}
+
+	return schema
+}
+
+func genaiType(t Type) genai.Type {
+	switch t {
+	case Object:
+		return genai.TypeObject
+	case Array:
+		return genai.TypeArray
+	case Integer:
+		return genai.TypeInteger
+	case Number:
+		return genai.TypeNumber
+	case Boolean:
+		return genai.TypeBoolean
+	default:
+		return genai.TypeString
+	}
+}
+
+// describe returns the description with the bounds and the format appended, for schema types which can't express them.
+func (s *Schema) describe(bounds, format bool) string {
+	var constraints []string
+	if bounds && s.Minimum != nil {
+		constraints = append(constraints, "minimum "+strconv.FormatFloat(*s.Minimum, 'f', -1, 64))
+	}
+	if bounds && s.Maximum != nil {
+		constraints = append(constraints, "maximum "+strconv.FormatFloat(*s.Maximum, 'f', -1, 64))
+	}
+	if format && s.Format != "" {
+		constraints = append(constraints, "format "+s.Format)
+	}
+	if len(constraints) == 0 {
+		return s.Description
+	}
+
+	hint := fmt.Sprintf("(%s)", strings.Join(constraints, ", "))
+	if s.Description == "" {
+		return hint
+	}
+
+	return s.Description + " " + hint
+}
@@ -0,0 +1,220 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var numberPattern = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// ErrItemDropped is wrapped by the violations of objects in arrays, which are dropped by Repair.
+var ErrItemDropped = errors.New("dropped")
+
+// Repair coerces the decoded JSON value into the schema where the intent is clear and validates the result.
+// Numbers given as strings like "60 Min." are parsed, ranges like "2-6" become their lower bound or,
+// for schemas tagged range:"upper", their upper bound, whole numbers become integers and enum values
+// are matched case-insensitively. URLs starting with "www." get the https scheme, but aren't validated.
+// Null values are accepted for every type.
+// Objects in arrays which violate the schema are dropped, so one invalid object doesn't invalidate the others.
+// The error joins every violation of the schema with the path of the value, see OnlyDropped.
+func (s *Schema) Repair(value any) (any, error) {
+	var errs []error
+	value = s.repair(value, "", &errs)
+
+	return value, errors.Join(errs...)
+}
+
+// OnlyDropped reports whether all violations of the error of Repair are dropped objects,
+// so the repaired value is valid.
+func OnlyDropped(err error) bool {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return errors.Is(err, ErrItemDropped)
+	}
+
+	for _, err := range joined.Unwrap() {
+		if !errors.Is(err, ErrItemDropped) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Schema) repair(value any, path string, errs *[]error) any {
+	if value == nil {
+		return nil
+	}
+
+	fail := func(format string, args ...any) any {
+		if path == "" {
+			path = "arguments"
+		}
+		*errs = append(*errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+
+		return value
+	}
+
+	switch s.Type {
+	case Object:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail("expected type %s, got %s", s.Type, describeValue(value))
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				fail("missing required property %s", name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if schema, ok := s.Properties[name]; ok {
+				object[name] = schema.repair(object[name], join(path, name), errs)
+			}
+		}
+
+		return object
+	case Array:
+		array, ok := value.([]any)
+		if !ok {
+			return fail("expected type %s, got %s", s.Type, describeValue(value))
+		}
+		if s.Items.Type != Object {
+			for i, item := range array {
+				array[i] = s.Items.repair(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+
+			return array
+		}
+
+		valid := make([]any, 0, len(array))
+		for i, item := range array {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			var itemErrs []error
+			item = s.Items.repair(item, itemPath, &itemErrs)
+			if len(itemErrs) > 0 && !OnlyDropped(errors.Join(itemErrs...)) {
+				*errs = append(*errs, fmt.Errorf("%s %w: %w", itemPath, ErrItemDropped, errors.Join(itemErrs...)))
+
+				continue
+			}
+			// objects nested in the item were dropped, the item itself is valid
+			*errs = append(*errs, itemErrs...)
+			valid = append(valid, item)
+		}
+
+		return valid
+	case Integer, Number:
+		number, ok := s.number(value)
+		if !ok {
+			return fail("expected type %s, got %s", s.Type, describeValue(value))
+		}
+		if s.Type == Integer {
+			number = math.Round(number)
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			return fail("%v is less than the minimum %v", number, *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fail("%v is greater than the maximum %v", number, *s.Maximum)
+		}
+
+		return number
+	case Boolean:
+		if _, ok := value.(bool); !ok {
+			return fail("expected type %s, got %s", s.Type, describeValue(value))
+		}
+
+		return value
+	default:
+		text, ok := value.(string)
+		if !ok {
+			switch value.(type) {
+			case float64, bool:
+				text = fmt.Sprint(value)
+			default:
+				return fail("expected type %s, got %s", s.Type, describeValue(value))
+			}
+		}
+
+		return s.repairString(text, fail)
+	}
+}
+
+func (s *Schema) repairString(text string, fail func(format string, args ...any) any) any {
+	if len(s.Enum) > 0 {
+		i := slices.IndexFunc(s.Enum, func(value string) bool {
+			return strings.EqualFold(value, strings.TrimSpace(text))
+		})
+		if i < 0 {
+			return fail("%q is not one of %s", text, strings.Join(s.Enum, ", "))
+		}
+
+		return s.Enum[i]
+	}
+
+	if s.Format == "uri" && text != "" {
+		text = strings.TrimSpace(text)
+		if strings.HasPrefix(strings.ToLower(text), "www.") {
+			text = "https://" + text
+		}
+		// relative references and values which are no http(s) URL, e.g. mailto: links, are
+		// left to the caller, so an invalid URL doesn't invalidate the object holding it
+	}
+
+	return text
+}
+
+// number returns the number of the value, parsing strings like "60 Min.", "2-6" or "4,5".
+func (s *Schema) number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		numbers := numberPattern.FindAllString(v, -1)
+		if len(numbers) == 0 {
+			return 0, false
+		}
+		number := numbers[0]
+		if s.UpperBound {
+			number = numbers[len(numbers)-1]
+		}
+		parsed, err := strconv.ParseFloat(strings.Replace(number, ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return parsed, true
+	default:
+		return 0, false
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+func describeValue(value any) string {
+	switch v := value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
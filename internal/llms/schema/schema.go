@@ -0,0 +1,199 @@
+// Package schema generates the JSON Schema of tool arguments from the struct tags of their type,
+// and adapts it to the schema types of the llm providers.
+//
+// The tags of a field are:
+//   - json: the property name, fields tagged "-" and unexported fields are left out
+//   - description: the description of the property
+//   - enum: the comma separated allowed values
+//   - required:"true": the property is required
+//   - minimum, maximum: the bounds of a number
+//   - format: the format of a string, e.g. uri
+//   - range:"upper": ranges like 2-6 are repaired to their upper bound instead of the lower one
+//
+// For slices of strings and numbers, enum, minimum, maximum and format apply to the items.
+package schema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Type is the JSON type of a schema.
+type Type string
+
+const (
+	Object  Type = "object"
+	Array   Type = "array"
+	String  Type = "string"
+	Integer Type = "integer"
+	Number  Type = "number"
+	Boolean Type = "boolean"
+)
+
+// Schema is the canonical JSON Schema of a value.
+type Schema struct {
+	Type        Type
+	Description string
+	Enum        []string
+	Format      string
+	Minimum     *float64
+	Maximum     *float64
+	// UpperBound repairs ranges to their upper bound, it is no part of JSON Schema.
+	UpperBound bool
+	// Items is the schema of the elements of an array.
+	Items      *Schema
+	Properties map[string]*Schema
+	Required   []string
+}
+
+// Generate returns the schema of the type of v, which may be a pointer.
+func Generate(v any) *Schema {
+	return generate(reflect.TypeOf(v))
+}
+
+func generate(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		schema := &Schema{
+			Type:       Object,
+			Properties: make(map[string]*Schema),
+		}
+		addFields(schema, t)
+
+		return schema
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  Array,
+			Items: generate(t.Elem()),
+		}
+	case reflect.Map:
+		return &Schema{Type: Object}
+	case reflect.Bool:
+		return &Schema{Type: Boolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Integer}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Number}
+	default:
+		return &Schema{Type: String}
+	}
+}
+
+// addFields adds the fields of the struct type as properties. Like encoding/json,
+// the fields of embedded structs without json name are promoted.
+func addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := propertyName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(schema, embedded)
+
+				continue
+			}
+		}
+
+		property := generate(field.Type)
+		property.Description = field.Tag.Get("description")
+		constrained := property
+		if property.Type == Array && property.Items.Type != Object && property.Items.Type != Array {
+			constrained = property.Items
+		}
+		addConstraints(constrained, field.Tag)
+
+		schema.Properties[name] = property
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func propertyName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, true
+}
+
+func addConstraints(schema *Schema, tag reflect.StructTag) {
+	if enum := tag.Get("enum"); enum != "" {
+		schema.Enum = strings.Split(enum, ",")
+	}
+	schema.Format = tag.Get("format")
+	schema.Minimum = parseBound(tag.Get("minimum"))
+	schema.Maximum = parseBound(tag.Get("maximum"))
+	schema.UpperBound = tag.Get("range") == "upper"
+}
+
+func parseBound(value string) *float64 {
+	if value == "" {
+		return nil
+	}
+
+	bound, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &bound
+}
+
+// Map returns the schema in the map form of JSON Schema, as used by the langchain and jamba tool definitions.
+func (s *Schema) Map() map[string]any {
+	m := map[string]any{
+		"type": string(s.Type),
+	}
+	if s.Description != "" {
+		m["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		m["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		m["format"] = s.Format
+	}
+	if s.Minimum != nil {
+		m["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		m["maximum"] = *s.Maximum
+	}
+	if s.Items != nil {
+		m["items"] = s.Items.Map()
+	}
+	if s.Type == Object && s.Properties != nil {
+		properties := map[string]any{}
+		for name, property := range s.Properties {
+			properties[name] = property.Map()
+		}
+		m["properties"] = properties
+	}
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+
+	return m
+}
@@ -0,0 +1,191 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/schema"
+)
+
+type base struct {
+	ID int `json:"id" required:"true"`
+}
+
+type address struct {
+	City string `json:"city" description:"City"`
+}
+
+type args struct {
+	base
+	Name     string    `json:"name,omitempty" description:"Name" required:"true"`
+	Rating   *float64  `json:"rating"         minimum:"0"        maximum:"5"`
+	Links    []string  `json:"links"          format:"uri"`
+	Tags     []string  `json:"tags"           enum:"a,b"`
+	Address  *address  `json:"address"`
+	Branches []address `json:"branches"`
+	Ignored  string    `json:"-"`
+	internal string
+}
+
+func TestGenerate(t *testing.T) {
+	got, err := json.Marshal(schema.Generate(&args{}).Map())
+	if err != nil {
+		t.Fatalf("Error marshaling schema: %v", err)
+	}
+
+	want := `{"properties":{` +
+		`"address":{"properties":{"city":{"description":"City","type":"string"}},"type":"object"},` +
+		`"branches":{"items":{"properties":{"city":{"description":"City","type":"string"}},"type":"object"},"type":"array"},` +
+		`"id":{"type":"integer"},` +
+		`"links":{"items":{"format":"uri","type":"string"},"type":"array"},` +
+		`"name":{"description":"Name","type":"string"},` +
+		`"rating":{"maximum":5,"minimum":0,"type":"number"},` +
+		`"tags":{"items":{"enum":["a","b"],"type":"string"},"type":"array"}},` +
+		`"required":["id","name"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("Unexpected schema\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	s := schema.Generate(args{})
+
+	definition := s.OpenAI()
+	if d := definition.Properties["rating"].Description; d != "(minimum 0, maximum 5)" {
+		t.Errorf("Unexpected OpenAI description of rating %q", d)
+	}
+	if items := definition.Properties["links"].Items; items == nil || items.Description != "(format uri)" {
+		t.Errorf("Unexpected OpenAI items of links %+v", items)
+	}
+
+	type strictArgs struct {
+		Name     string    `json:"name"     required:"true"`
+		Genre    string    `json:"genre"    enum:"a,b"`
+		Branches []address `json:"branches"`
+	}
+	strict, err := json.Marshal(schema.Generate(strictArgs{}).OpenAIStrict())
+	if err != nil {
+		t.Fatalf("Error marshaling strict schema: %v", err)
+	}
+	// every property is required, optional properties are nullable instead
+	want := `{"additionalProperties":false,"properties":{` +
+		`"branches":{"items":{"additionalProperties":false,"properties":{"city":{"description":"City","type":["string","null"]}},"required":["city"],"type":"object"},"type":["array","null"]},` +
+		`"genre":{"enum":["a","b",null],"type":["string","null"]},` +
+		`"name":{"type":"string"}},` +
+		`"required":["branches","genre","name"],"type":"object"}`
+	if string(strict) != want {
+		t.Errorf("Unexpected strict schema\n got %s\nwant %s", strict, want)
+	}
+
+	genai := s.Genai()
+	if rating := genai.Properties["rating"]; rating.Maximum != 5 || rating.Description != "" {
+		t.Errorf("Unexpected genai rating %+v", rating)
+	}
+	if city := genai.Properties["branches"].Items.Properties["city"]; city.Description != "City" {
+		t.Errorf("Unexpected genai city %+v", city)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	type room struct {
+		PlayersMin int    `json:"players_min" minimum:"0" maximum:"100"`
+		PlayersMax int    `json:"players_max" minimum:"0" maximum:"100" range:"upper"`
+		Duration   int    `json:"duration"    minimum:"0" maximum:"600"`
+		URL        string `json:"url"         format:"uri"`
+		Genre      string `json:"genre"       enum:"Horror,Crime" required:"true"`
+		Difficulty string `json:"difficulty"`
+	}
+	s := schema.Generate(room{})
+
+	tests := []struct {
+		in, want string
+		wantErr  string
+	}{
+		{
+			in:   `{"players_min":"2-6","players_max":"2 bis 6 Spieler","duration":"60 Min.","url":" www.example.com/a ","genre":"crime","difficulty":3}`,
+			want: `{"difficulty":"3","duration":60,"genre":"Crime","players_max":6,"players_min":2,"url":"https://www.example.com/a"}`,
+		},
+		{
+			in:   `{"players_min":4.4,"url":"/rooms/a","genre":"Horror"}`,
+			want: `{"genre":"Horror","players_min":4,"url":"/rooms/a"}`,
+		},
+		{
+			in:   `{"url":"mailto:info@example.com","genre":"Horror"}`,
+			want: `{"genre":"Horror","url":"mailto:info@example.com"}`,
+		},
+		{
+			in:      `{"players_min":"viele","duration":900,"url":"javascript:void(0)","genre":"Thriller"}`,
+			wantErr: "duration: 900 is greater than the maximum 600\ngenre: \"Thriller\" is not one of Horror, Crime\nplayers_min: expected type integer, got \"viele\"",
+		},
+		{
+			in:      `{"duration":60}`,
+			wantErr: "arguments: missing required property genre",
+		},
+	}
+	for _, tt := range tests {
+		var value any
+		err := json.Unmarshal([]byte(tt.in), &value)
+		if err != nil {
+			t.Fatalf("Error unmarshaling %s: %v", tt.in, err)
+		}
+
+		repaired, err := s.Repair(value)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: expected error %q, got %v", tt.in, tt.wantErr, err)
+			}
+
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.in, err)
+
+			continue
+		}
+		got, err := json.Marshal(repaired)
+		if err != nil {
+			t.Fatalf("Error marshaling %v: %v", repaired, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestRepairDropsItems(t *testing.T) {
+	type room struct {
+		Name  string `json:"name"  required:"true"`
+		Genre string `json:"genre" enum:"Horror,Crime"`
+	}
+	type rooms struct {
+		Rooms []room `json:"rooms" required:"true"`
+	}
+
+	var value any
+	err := json.Unmarshal([]byte(`{"rooms":[{"name":"Die Gruft","genre":"horror"},{"name":"Das Labor","genre":"Thriller"},{"genre":"Crime"}]}`), &value)
+	if err != nil {
+		t.Fatalf("Error unmarshaling rooms: %v", err)
+	}
+
+	repaired, err := schema.Generate(rooms{}).Repair(value)
+	if !schema.OnlyDropped(err) {
+		t.Fatalf("Expected only dropped rooms, got %v", err)
+	}
+	want := "rooms[1] dropped: rooms[1].genre: \"Thriller\" is not one of Horror, Crime\nrooms[2] dropped: rooms[2]: missing required property name"
+	if err.Error() != want {
+		t.Errorf("Expected error %q, got %q", want, err)
+	}
+	got, err := json.Marshal(repaired)
+	if err != nil {
+		t.Fatalf("Error marshaling %v: %v", repaired, err)
+	}
+	if string(got) != `{"rooms":[{"genre":"Horror","name":"Die Gruft"}]}` {
+		t.Errorf("Expected the valid room only, got %s", got)
+	}
+
+	// violations outside of the rooms aren't dropped
+	_, err = schema.Generate(rooms{}).Repair(map[string]any{})
+	if err == nil || schema.OnlyDropped(err) {
+		t.Errorf("Expected the missing rooms to fail, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package llms
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// imageTokens is the estimated cost of an attached screenshot.
+	imageTokens = 1500
+	// turnTokens is the estimated overhead of the wire format of a single turn.
+	turnTokens = 8
+)
+
+// EstimateTokens estimates the token count of the text without a provider specific tokenizer.
+// Runs of letters and digits count one token per four characters, every other
+// non-space character (e.g. the brackets of html tags) counts as a token of its own.
+func EstimateTokens(text string) int {
+	tokens := 0
+	word := 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// EstimateTokens estimates the token count of the whole conversation.
+func (c *Conversation) EstimateTokens() int {
+	tokens := 0
+	for _, turn := range c.Turns {
+		tokens += turn.estimateTokens()
+	}
+
+	return tokens
+}
+
+func (t Turn) estimateTokens() int {
+	tokens := turnTokens + EstimateTokens(t.Text)
+	tokens += len(t.Images) * imageTokens
+	for _, toolCall := range t.ToolCalls {
+		tokens += turnTokens + EstimateTokens(toolCall.Name) + EstimateTokens(toolCall.Arguments)
+	}
+
+	return tokens
+}
+
+// FitTokenBudget shrinks the conversation until its estimated token count is within the budget.
+// The turns after the last assistant turn are the current step, which the llm has yet to answer.
+// The text of the older user and tool turns is replaced by the placeholder first. If
+// that is not enough, the screenshots of the current step are dropped, the bottom tiles first,
+// and then its texts are cut proportionally. It reports whether anything was changed.
+func (c *Conversation) FitTokenBudget(budget int, placeholder func(turn Turn) string) bool {
+	current := c.currentStep()
+	changed := false
+	tokens := c.EstimateTokens()
+	for i := 0; i < current && tokens > budget; i++ {
+		turn := &c.Turns[i]
+		if turn.Role != RoleUser && turn.Role != RoleTool {
+			continue
+		}
+
+		replacement := placeholder(*turn)
+		if replacement == turn.Text && len(turn.Images) == 0 {
+			continue
+		}
+
+		before := turn.estimateTokens()
+		turn.Text = replacement
+		turn.Images = nil
+		tokens += turn.estimateTokens() - before
+		changed = true
+	}
+
+	for i := len(c.Turns) - 1; i >= current && tokens > budget; i-- {
+		turn := &c.Turns[i]
+		for len(turn.Images) > 0 && tokens > budget {
+			turn.Images = turn.Images[:len(turn.Images)-1]
+			tokens -= imageTokens
+			changed = true
+		}
+	}
+	if tokens <= budget {
+		return changed
+	}
+
+	// cut the texts proportionally to the tokens which are too many
+	excess := tokens - budget
+	stepTokens := 0
+	for _, turn := range c.Turns[current:] {
+		stepTokens += EstimateTokens(turn.Text)
+	}
+	for i := current; i < len(c.Turns); i++ {
+		turn := &c.Turns[i]
+		if excess >= stepTokens {
+			turn.Text = ""
+			changed = true
+
+			continue
+		}
+
+		keep := len(turn.Text) * (stepTokens - excess) / stepTokens
+		for keep > 0 && !utf8.RuneStart(turn.Text[keep]) {
+			keep--
+		}
+		if keep < len(turn.Text) {
+			turn.Text = turn.Text[:keep]
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// currentStep returns the index of the first turn after the last assistant turn.
+func (c *Conversation) currentStep() int {
+	for i := len(c.Turns) - 1; i >= 0; i-- {
+		if c.Turns[i].Role == RoleAssistant {
+			return i + 1
+		}
+	}
+
+	return 0
+}
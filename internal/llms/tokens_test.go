@@ -0,0 +1,131 @@
+package llms_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"room", 1},
+		{"escape room", 3},
+		{"<p>Horror</p>", 9},
+	}
+
+	for _, tt := range tests {
+		if got := llms.EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFitTokenBudget(t *testing.T) {
+	page := strings.Repeat("<div>Escape Room</div>", 500)
+	conversation := llms.NewConversation()
+	conversation.AddUser("task " + page)
+	conversation.AddAssistant("", llms.ToolCall{ID: "1", Name: llms.URLsName, Arguments: `{"urls":["https://example.com"]}`})
+	conversation.AddToolResult("1", llms.URLsName, page)
+
+	lastTokens := llms.EstimateTokens(conversation.Turns[2].Text)
+	placeholder := func(llms.Turn) string {
+		return "task dropped"
+	}
+
+	if !conversation.FitTokenBudget(lastTokens+100, placeholder) {
+		t.Fatal("Expected the conversation to be compacted")
+	}
+	if conversation.Turns[0].Text != "task dropped" {
+		t.Errorf("Expected the oldest turn to be replaced, got %q", conversation.Turns[0].Text[:20])
+	}
+	if conversation.Turns[2].Text != page {
+		t.Error("Expected the last turn to be kept")
+	}
+
+	if !conversation.FitTokenBudget(100, placeholder) {
+		t.Fatal("Expected the last turn to be cut")
+	}
+	if got := conversation.EstimateTokens(); got > 150 {
+		t.Errorf("Expected about 100 tokens, got %d", got)
+	}
+}
+
+func TestFitTokenBudgetImages(t *testing.T) {
+	page := strings.Repeat("<div>Escape Room</div>", 50)
+	tiles := [][]byte{[]byte("top"), []byte("middle"), []byte("bottom")}
+	conversation := llms.NewConversation()
+	conversation.AddUser("task")
+	conversation.AddUser(page, tiles...)
+
+	// the screenshots alone exceed the budget, the bottom tiles are dropped first
+	budget := conversation.EstimateTokens() - 2000
+	if !conversation.FitTokenBudget(budget, func(llms.Turn) string { return "task" }) {
+		t.Fatal("Expected the conversation to be compacted")
+	}
+	if got := conversation.EstimateTokens(); got > budget {
+		t.Errorf("Expected at most %d tokens, got %d", budget, got)
+	}
+	last := conversation.Last()
+	if len(last.Images) != 1 || string(last.Images[0]) != "top" {
+		t.Errorf("Expected the top tile to be kept, got %d tiles", len(last.Images))
+	}
+	if last.Text != page {
+		t.Error("Expected the text of the last turn to be kept")
+	}
+
+	// without room for a single screenshot the text is cut
+	if !conversation.FitTokenBudget(100, func(llms.Turn) string { return "task" }) {
+		t.Fatal("Expected the last turn to be cut")
+	}
+	if last := conversation.Last(); len(last.Images) != 0 || conversation.EstimateTokens() > 150 {
+		t.Errorf("Expected no tiles and about 100 tokens, got %d tiles and %d tokens", len(last.Images), conversation.EstimateTokens())
+	}
+}
+
+func TestFitTokenBudgetCurrentStep(t *testing.T) {
+	page := strings.Repeat("<div>Escape Room</div>", 500)
+	conversation := llms.NewConversation()
+	conversation.AddUser("task " + page)
+	conversation.AddAssistant("",
+		llms.ToolCall{ID: "1", Name: llms.URLsName, Arguments: `{"urls":["https://example.com/a"]}`},
+		llms.ToolCall{ID: "2", Name: llms.URLsName, Arguments: `{"urls":["https://example.com/b"]}`},
+	)
+	conversation.AddToolResult("1", llms.URLsName, page)
+	conversation.AddToolResult("2", llms.URLsName, page)
+	conversation.AddUser("screenshots", []byte("top"), []byte("bottom"))
+	placeholder := func(llms.Turn) string {
+		return "dropped"
+	}
+
+	// every turn after the last assistant turn is kept while older turns can be compacted
+	budget := conversation.EstimateTokens() - llms.EstimateTokens(page) + 10
+	if !conversation.FitTokenBudget(budget, placeholder) {
+		t.Fatal("Expected the conversation to be compacted")
+	}
+	if conversation.Turns[0].Text != "dropped" {
+		t.Errorf("Expected the oldest turn to be replaced, got %q", conversation.Turns[0].Text[:20])
+	}
+	if conversation.Turns[2].Text != page || conversation.Turns[3].Text != page || len(conversation.Turns[4].Images) != 2 {
+		t.Error("Expected the current step to be kept")
+	}
+
+	// then the screenshots are dropped and the texts of the step are cut evenly
+	if !conversation.FitTokenBudget(llms.EstimateTokens(page), placeholder) {
+		t.Fatal("Expected the current step to be cut")
+	}
+	if len(conversation.Turns[4].Images) != 0 {
+		t.Errorf("Expected no tiles, got %d", len(conversation.Turns[4].Images))
+	}
+	first, second := len(conversation.Turns[2].Text), len(conversation.Turns[3].Text)
+	if first == 0 || first == len(page) || first != second {
+		t.Errorf("Expected both tool results to be cut to the same length, got %d and %d", first, second)
+	}
+	if got := conversation.EstimateTokens(); got > llms.EstimateTokens(page) {
+		t.Errorf("Expected at most %d tokens, got %d", llms.EstimateTokens(page), got)
+	}
+}
@@ -0,0 +1,152 @@
+package llms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/schema"
+)
+
+var (
+	// ErrUnknownTool is wrapped by the ToolError of a call of a tool which isn't registered.
+	ErrUnknownTool = errors.New("unknown tool")
+	// ErrInvalidArguments is wrapped by the ToolError of a call whose arguments don't match the tool.
+	ErrInvalidArguments = errors.New("invalid tool arguments")
+)
+
+// ToolError is a failed tool call. Its message is meant to be sent back to the llm as
+// result of the call, so it can correct the call.
+type ToolError struct {
+	Call ToolCall
+	Err  error
+}
+
+func (e *ToolError) Error() string {
+	return fmt.Sprintf("tool call %s failed: %v", e.Call.Name, e.Err)
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// Tool is a function the llm can call. Its arguments are repaired against the schema of the argument type
+// and decoded into a value of it, which the handler copies into the response.
+type Tool struct {
+	name        string
+	description string
+	schema      *schema.Schema
+	args        func() any
+	handle      func(args any, resp *LlmResposeWithChatID)
+}
+
+// NewTool declares a tool with arguments of type A.
+func NewTool[A any](name, description string, handle func(args A, resp *LlmResposeWithChatID)) Tool {
+	return Tool{
+		name:        name,
+		description: description,
+		schema:      schema.Generate(*new(A)),
+		args: func() any {
+			return new(A)
+		},
+		handle: func(args any, resp *LlmResposeWithChatID) {
+			handle(*args.(*A), resp)
+		},
+	}
+}
+
+func (t Tool) Name() string {
+	return t.name
+}
+
+func (t Tool) Description() string {
+	return t.description
+}
+
+// Schema returns the schema of the argument type, the plugins declare the parameters of the tool with it.
+func (t Tool) Schema() *schema.Schema {
+	return t.schema
+}
+
+// ToolRegistry holds the tools offered to the llm, in the order they are declared to it.
+type ToolRegistry struct {
+	tools []Tool
+}
+
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	return &ToolRegistry{tools: tools}
+}
+
+// All returns the tools in declaration order.
+func (r *ToolRegistry) All() []Tool {
+	return r.tools
+}
+
+// Get returns the tool with the name.
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	for _, tool := range r.tools {
+		if tool.name == name {
+			return tool, true
+		}
+	}
+
+	return Tool{}, false
+}
+
+// Decode turns a tool call of the llm into a response. Arguments the schema of the tool can't repair,
+// see schema.Schema.Repair, fail like calls of unknown tools with a ToolError wrapping ErrInvalidArguments
+// or ErrUnknownTool. If only objects in arrays were dropped, the response holds the valid ones
+// and the ToolError wraps schema.ErrItemDropped too.
+func (r *ToolRegistry) Decode(call ToolCall) (LlmResposeWithChatID, error) {
+	resp := LlmResposeWithChatID{
+		ChatID:   call.ID,
+		ToolName: call.Name,
+	}
+
+	tool, ok := r.Get(call.Name)
+	if !ok {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w %q", ErrUnknownTool, call.Name)}
+	}
+
+	var raw any
+	err := json.Unmarshal([]byte(call.Arguments), &raw)
+	if err != nil {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w: %w", ErrInvalidArguments, err)}
+	}
+	raw, repairErr := tool.schema.Repair(raw)
+	if repairErr != nil && !schema.OnlyDropped(repairErr) {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w: %w", ErrInvalidArguments, repairErr)}
+	}
+	repaired, err := json.Marshal(raw)
+	if err != nil {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w: %w", ErrInvalidArguments, err)}
+	}
+
+	args := tool.args()
+	err = json.Unmarshal(repaired, args)
+	if err != nil {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w: %w", ErrInvalidArguments, err)}
+	}
+	tool.handle(args, &resp)
+
+	// the valid objects, e.g. rooms, are kept and the llm is asked to correct the dropped ones
+	if repairErr != nil {
+		return resp, &ToolError{Call: call, Err: fmt.Errorf("%w: %w", ErrInvalidArguments, repairErr)}
+	}
+
+	return resp, nil
+}
+
+var (
+	// URLsTool requests the content of more pages.
+	URLsTool = NewTool(URLsName, URLsDescription, func(args UrlsResp, resp *LlmResposeWithChatID) {
+		resp.UrlsResp = args
+	})
+	// RoomsTool lists the escape rooms and ends the conversation.
+	RoomsTool = NewTool(RoomsName, RoomsDescription, func(args RoomsResp, resp *LlmResposeWithChatID) {
+		resp.RoomsResp = args
+	})
+
+	// Tools are the tools of a crawl.
+	Tools = NewToolRegistry(RoomsTool, URLsTool)
+)
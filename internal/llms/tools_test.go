@@ -0,0 +1,88 @@
+package llms_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms/schema"
+)
+
+func TestToolRegistryDecode(t *testing.T) {
+	resp, err := llms.Tools.Decode(llms.ToolCall{ID: "1", Name: llms.URLsName, Arguments: `{"urls":["https://example.com/rooms"]}`})
+	if err != nil {
+		t.Fatalf("Error decoding call: %v", err)
+	}
+	if resp.ChatID != "1" || resp.ToolName != llms.URLsName || !slices.Equal(resp.URLs, []string{"https://example.com/rooms"}) {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	resp, err = llms.Tools.Decode(llms.ToolCall{ID: "2", Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","duration":"60 Min.","players_min":"2-6","players_max":"2-6","genre":"horror"}]}`})
+	if err != nil {
+		t.Fatalf("Error decoding call: %v", err)
+	}
+	if len(resp.Rooms) != 1 || resp.Rooms[0].Duration != 60 || resp.Rooms[0].PlayersMin != 2 || resp.Rooms[0].PlayersMax != 6 || resp.Rooms[0].Genre != "Horror" {
+		t.Errorf("Unexpected rooms %+v", resp.Rooms)
+	}
+
+	// invalid rooms are dropped, the valid ones kept
+	resp, err = llms.Tools.Decode(llms.ToolCall{ID: "3", Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Horror"},{"name":"Das Labor","genre":"Thriller"}]}`})
+	if !errors.Is(err, llms.ErrInvalidArguments) || !errors.Is(err, schema.ErrItemDropped) {
+		t.Errorf("Expected the invalid room to be dropped, got %v", err)
+	}
+	if len(resp.Rooms) != 1 || resp.Rooms[0].Name != "Die Gruft" {
+		t.Errorf("Expected the valid room to be kept, got %+v", resp.Rooms)
+	}
+
+	// URLs which are no http(s) URL are validated by the agent, they don't drop the room
+	resp, err = llms.Tools.Decode(llms.ToolCall{ID: "4", Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Horror","booking_url":"mailto:info@example.com"}]}`})
+	if err != nil || len(resp.Rooms) != 1 || resp.Rooms[0].BookingURL != "mailto:info@example.com" {
+		t.Errorf("Expected the room with its booking URL, got %+v and %v", resp.Rooms, err)
+	}
+
+	tests := []struct {
+		call llms.ToolCall
+		want error
+	}{
+		{call: llms.ToolCall{ID: "3", Name: "navigate", Arguments: `{}`}, want: llms.ErrUnknownTool},
+		{call: llms.ToolCall{ID: "4", Name: llms.URLsName, Arguments: `{"urls":"https://example.com"}`}, want: llms.ErrInvalidArguments},
+		{call: llms.ToolCall{ID: "5", Name: llms.RoomsName, Arguments: `{"rooms":[`}, want: llms.ErrInvalidArguments},
+		{call: llms.ToolCall{ID: "6", Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Thriller"}]}`}, want: llms.ErrInvalidArguments},
+		{call: llms.ToolCall{ID: "7", Name: llms.RoomsName, Arguments: `{"rooms":[{"name":"Die Gruft","genre":"Horror","duration":"lang"}]}`}, want: llms.ErrInvalidArguments},
+	}
+	for _, tt := range tests {
+		resp, err := llms.Tools.Decode(tt.call)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.call.Arguments, tt.want, err)
+		}
+		var toolErr *llms.ToolError
+		if !errors.As(err, &toolErr) || toolErr.Call.ID != tt.call.ID || resp.ChatID != tt.call.ID {
+			t.Errorf("%s: expected a tool error of the call, got %v", tt.call.Arguments, err)
+		}
+	}
+}
+
+// TestToolSchemas compares the schemas of the llm arguments with testdata/schemas.json.
+func TestToolSchemas(t *testing.T) {
+	schemas := map[string]any{
+		llms.URLsName:  llms.URLsTool.Schema().Map(),
+		llms.RoomsName: llms.RoomsTool.Schema().Map(),
+		"response":     schema.Generate(llms.LlmResponse{}).Map(),
+	}
+	got, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		t.Fatalf("Error marshaling schemas: %v", err)
+	}
+
+	want, err := os.ReadFile("testdata/schemas.json")
+	if err != nil {
+		t.Fatalf("Error reading golden schemas: %v", err)
+	}
+	if string(got) != strings.TrimSpace(string(want)) {
+		t.Errorf("Unexpected schemas\n got %s\nwant %s", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package vertex
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,12 +13,19 @@ import (
 
 var _ = (llms.Plugin)(&vertex{})
 
+// contextWindows holds the context window sizes of the known models in tokens.
+var contextWindows = map[string]int{
+	"gemini-1.5-flash-001": 1048576,
+	"gemini-1.5-pro-001":   2097152,
+}
+
+const defaultContextWindow = 32000
+
 type vertex struct {
 	client       *genai.Client
 	model        *genai.GenerativeModel
-	messages     []genai.Part
-	chatSession  *genai.ChatSession
 	guided       bool
+	roomToolOnly bool
 	imageSupport bool
 }
 
@@ -31,22 +39,15 @@ func New(client *genai.Client, modelName string, temperature float32, imageSuppo
 		},
 	}
 
-	model.Tools = []*genai.Tool{
-		{
-			FunctionDeclarations: []*genai.FunctionDeclaration{
-				{
-					Name:        llms.URLsName,
-					Description: llms.URLsDescription,
-					Parameters:  generateSchema(llms.UrlsResp{}),
-				},
-				{
-					Name:        llms.RoomsName,
-					Description: llms.RoomsDescription,
-					Parameters:  generateSchema(llms.RoomsResp{}),
-				},
-			},
-		},
+	declarations := []*genai.FunctionDeclaration{}
+	for _, tool := range llms.Tools.All() {
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:        tool.Name(),
+			Description: tool.Description(),
+			Parameters:  tool.Schema().Genai(),
+		})
 	}
+	model.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
 
 	model.SystemInstruction = &genai.Content{
 		Parts: []genai.Part{genai.Text(llms.SystemPrompt)},
@@ -59,36 +60,84 @@ func New(client *genai.Client, modelName string, temperature float32, imageSuppo
 	}
 }
 
-func (v *vertex) AddPrompt(image []byte, text, _, functionName string) {
-	// img := genai.ImageData("webp", image)
-	if functionName == "" {
-		v.chatSession = v.model.StartChat()
-	}
+// contents translates the conversation to genai contents. Consecutive turns of
+// the same genai role are merged, because gemini expects alternating roles.
+func (v *vertex) contents(conversation *llms.Conversation) ([]*genai.Content, error) {
+	contents := []*genai.Content{}
+	for _, turn := range conversation.Turns {
+		role := "user"
+		parts := []genai.Part{}
+		switch turn.Role {
+		case llms.RoleSystem, llms.RoleUser:
+			parts = append(parts, genai.Text(turn.Text))
+			for _, image := range turn.Images {
+				parts = append(parts, genai.ImageData("webp", image))
+			}
+		case llms.RoleAssistant:
+			role = "model"
+			if turn.Text != "" {
+				parts = append(parts, genai.Text(turn.Text))
+			}
+			for _, toolCall := range turn.ToolCalls {
+				args := map[string]any{}
+				err := json.Unmarshal([]byte(toolCall.Arguments), &args)
+				if err != nil {
+					return nil, fmt.Errorf("failed to unmarshal arg: %w", err)
+				}
+				parts = append(parts, genai.FunctionCall{
+					Name: toolCall.Name,
+					Args: args,
+				})
+			}
+		case llms.RoleTool:
+			parts = append(parts, genai.FunctionResponse{
+				Name:     turn.ToolName,
+				Response: map[string]any{"content": turn.Text},
+			})
+		}
+
+		if len(contents) > 0 && contents[len(contents)-1].Role == role {
+			contents[len(contents)-1].Parts = append(contents[len(contents)-1].Parts, parts...)
 
-	if v.guided {
-		v.model.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{llms.URLsName}
+			continue
+		}
+		contents = append(contents, &genai.Content{
+			Role:  role,
+			Parts: parts,
+		})
 	}
 
-	genAIPart := []genai.Part{
-		// img,
-		genai.Text(text),
+	return contents, nil
+}
+
+// allowedFunctions returns the functions gemini may call, it has to call one of them.
+func (v *vertex) allowedFunctions(conversation *llms.Conversation) []string {
+	if v.roomToolOnly {
+		return []string{llms.RoomsName}
 	}
-	if functionName != "" {
-		v.model.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{llms.URLsName, llms.RoomsName}
-		genAIPart = []genai.Part{
-			genai.FunctionResponse{
-				Name:     functionName,
-				Response: map[string]any{"content": text},
-			},
-		}
+	if v.guided && !conversation.HasAssistant() {
+		return []string{llms.URLsName}
 	}
 
-	v.messages = genAIPart
+	return []string{llms.URLsName, llms.RoomsName}
 }
 
-func (v *vertex) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+func (v *vertex) ExecutePrompt(ctx context.Context, conversation *llms.Conversation) ([]llms.LlmResposeWithChatID, time.Duration, int, error) {
+	contents, err := v.contents(conversation)
+	if err != nil {
+		return nil, time.Duration(0), 0, err
+	}
+	if len(contents) == 0 || contents[len(contents)-1].Role != "user" {
+		return nil, time.Duration(0), 0, errors.New("conversation does not end with a user turn")
+	}
+
+	v.model.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = v.allowedFunctions(conversation)
+
+	chatSession := v.model.StartChat()
+	chatSession.History = contents[:len(contents)-1]
+
 	startTime := time.Now()
-	resp, err := v.chatSession.SendMessage(ctx, v.messages...)
+	resp, err := chatSession.SendMessage(ctx, contents[len(contents)-1].Parts...)
 	duration := time.Since(startTime)
 	if err != nil {
 		return nil, duration, 0, fmt.Errorf("GenerateContent error: %w", err)
@@ -100,35 +149,28 @@ func (v *vertex) ExecutePrompt(ctx context.Context) ([]llms.LlmResposeWithChatID
 	}
 
 	result := []llms.LlmResposeWithChatID{}
+	toolCalls := []llms.ToolCall{}
 	for _, part := range resp.Candidates {
 		for _, fCall := range part.FunctionCalls() {
-			var args interface{} = &llms.RoomsResp{}
-			if fCall.Name == llms.URLsName {
-				args = &llms.UrlsResp{}
-			}
-
 			jsonArg, err := json.Marshal(fCall.Args)
 			if err != nil {
 				return nil, time.Duration(0), tokenCount, fmt.Errorf("failed to marshal arg: %w", err)
 			}
-			err = json.Unmarshal(jsonArg, args)
-			if err != nil {
-				return nil, time.Duration(0), tokenCount, fmt.Errorf("failed to unmarshal arg: %w", err)
-			}
-
-			resp := llms.LlmResposeWithChatID{
-				ToolName: fCall.Name,
-			}
 
-			if urls, ok := args.(*llms.UrlsResp); ok {
-				resp.URLs = urls.URLs
-			} else if rooms, ok := args.(*llms.RoomsResp); ok {
-				resp.Rooms = rooms.Rooms
+			// gemini does not identify function calls, so the ids are only unique within the conversation
+			toolCall := llms.ToolCall{
+				ID:        fmt.Sprintf("%s-%d-%d", fCall.Name, conversation.Len(), len(toolCalls)),
+				Name:      fCall.Name,
+				Arguments: string(jsonArg),
 			}
+			toolCalls = append(toolCalls, toolCall)
 
+			resp, err := llms.Tools.Decode(toolCall)
+			resp.Err = err
 			result = append(result, resp)
 		}
 	}
+	conversation.AddAssistant("", toolCalls...)
 
 	return result, duration, tokenCount, nil
 }
@@ -141,10 +183,16 @@ func (v *vertex) ImageSupport() bool {
 	return v.imageSupport
 }
 
+func (v *vertex) ContextWindow() int {
+	if window, ok := contextWindows[v.model.Name()]; ok {
+		return window
+	}
+
+	return defaultContextWindow
+}
+
 func (v *vertex) ResetChat() {
-	v.model.ToolConfig.FunctionCallingConfig.AllowedFunctionNames = []string{llms.URLsName, llms.RoomsName}
-	v.chatSession.History = nil
-	v.messages = nil
+	v.roomToolOnly = false
 }
 
 func (v *vertex) Guided(mode bool) {
@@ -152,4 +200,6 @@ func (v *vertex) Guided(mode bool) {
 }
 
 func (v *vertex) RoomToolOnly() {
+	v.guided = false
+	v.roomToolOnly = true
 }
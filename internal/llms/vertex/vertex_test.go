@@ -0,0 +1,33 @@
+package vertex
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+func TestAllowedFunctions(t *testing.T) {
+	conversation := llms.NewConversation()
+	conversation.AddUser("List all escape rooms.")
+	v := &vertex{}
+
+	if got := v.allowedFunctions(conversation); !slices.Equal(got, []string{llms.URLsName, llms.RoomsName}) {
+		t.Errorf("Expected both tools, got %v", got)
+	}
+
+	v.Guided(true)
+	if got := v.allowedFunctions(conversation); !slices.Equal(got, []string{llms.URLsName}) {
+		t.Errorf("Expected the guided first prompt to allow the more content tool only, got %v", got)
+	}
+
+	// chunks are extracted with the rooms tool only until the chat is reset
+	v.RoomToolOnly()
+	if got := v.allowedFunctions(conversation); !slices.Equal(got, []string{llms.RoomsName}) {
+		t.Errorf("Expected the rooms tool only, got %v", got)
+	}
+	v.ResetChat()
+	if got := v.allowedFunctions(conversation); !slices.Equal(got, []string{llms.URLsName, llms.RoomsName}) {
+		t.Errorf("Expected both tools after the reset, got %v", got)
+	}
+}
@@ -1,11 +1,17 @@
 package output
 
 import (
+	"bytes"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/gocarina/gocsv"
+	"github.com/martinbockt/esc-llm-webscraper/internal/identity"
 )
 
 type Information struct {
@@ -16,6 +22,8 @@ type Information struct {
 	WebsitesChecked      int           `csv:"Websites Checked"`
 	WebsiteMaxLength     int           `csv:"Website Max Length"`
 	WebsiteReducedLength int           `csv:"Website Reduced Length"`
+	ContentFormat        string        `csv:"Content Format"`
+	VisionMode           string        `csv:"Vision Mode"`
 	TokenCount           int           `csv:"Token Count"`
 	ProviderURL          string        `csv:"Provider URL"`
 	ProviderName         string        `csv:"Provider Name"`
@@ -30,6 +38,11 @@ type Information struct {
 	Genre                string        `csv:"Genre"`
 	Difficulty           string        `csv:"Difficulty"`
 	TokenLimitReached    bool          `csv:"Token Limit Reached"`
+	ContextCompactions   int           `csv:"Context Compactions"`
+	ChunksExtracted      int           `csv:"Chunks Extracted"`
+	ConsentBanners       int           `csv:"Consent Banners"`
+	InvalidURLs          int           `csv:"Invalid URLs"`
+	ToolRetries          int           `csv:"Tool Retries"`
 	Error                string        `csv:"Error"`
 }
 
@@ -42,14 +55,14 @@ func New() *Output {
 }
 
 func (o *Output) AddInformation(i Information) {
-	for _, info := range o.information {
-		if info.RoomName != i.RoomName || info.ID != i.ID || info.LLM != i.LLM || info.DetailPageURL != i.DetailPageURL {
+	for index, info := range o.information {
+		if info.ID != i.ID || info.LLM != i.LLM || !sameRoom(info, i) {
 			continue
 		}
 		tokenCount := info.TokenCount
-		info = i
-		if info.TokenCount == 0 {
-			info.TokenCount = tokenCount
+		o.information[index] = i
+		if i.TokenCount == 0 {
+			o.information[index].TokenCount = tokenCount
 		}
 
 		return
@@ -57,6 +70,18 @@ func (o *Output) AddInformation(i Information) {
 	o.information = append(o.information, i)
 }
 
+// sameRoom reports whether both rows describe the same room. Rows without a room describe the provider.
+func sameRoom(a, b Information) bool {
+	if a.RoomName == "" && b.RoomName == "" {
+		return a.DetailPageURL == b.DetailPageURL
+	}
+
+	return identity.Same(
+		identity.Room{Name: a.RoomName, DetailPageURL: a.DetailPageURL},
+		identity.Room{Name: b.RoomName, DetailPageURL: b.DetailPageURL},
+	)
+}
+
 func (o *Output) SaveAsCSV(llmName string) error {
 	file, err := os.Create(llmName + "output.csv")
 	if err != nil {
@@ -73,21 +98,77 @@ func (o *Output) SaveAsCSV(llmName string) error {
 }
 
 func (o *Output) ReadOutputCSV(llmName string) ([]Information, error) {
-	file, err := os.Open(llmName + "output.csv")
-	if os.IsNotExist(err) {
+	information, err := ReadCSVFile(llmName + "output.csv")
+	if errors.Is(err, os.ErrNotExist) {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	o.information = information
+
+	return information, nil
+}
+
+// durationColumns are written as formatted durations, e.g. "37.06s", which gocsv reads as plain integers only.
+var durationColumns = []string{"LLM Duration", "Request Duration"}
+
+// ReadCSVFile reads the information of a CSV file written by SaveAsCSV.
+func ReadCSVFile(filename string) ([]Information, error) {
+	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+
+	err = parseDurations(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = csv.NewWriter(&buf).WriteAll(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write csv: %w", err)
+	}
+
 	var information []Information
-	if err := gocsv.UnmarshalFile(file, &information); err != nil {
+	if err := gocsv.UnmarshalBytes(buf.Bytes(), &information); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal file to information: %w", err)
 	}
 
-	o.information = information
-
 	return information, nil
 }
+
+// parseDurations replaces the formatted durations of the records by nanoseconds.
+func parseDurations(records [][]string) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	for column, name := range records[0] {
+		if !slices.Contains(durationColumns, name) {
+			continue
+		}
+
+		for _, record := range records[1:] {
+			if column >= len(record) || record[column] == "" {
+				continue
+			}
+
+			d, err := time.ParseDuration(record[column])
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", name, err)
+			}
+			record[column] = strconv.FormatInt(int64(d), 10)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package scraper
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	archiveIndex   = "index.jsonl"
+	htmlFile       = "page.html"
+	contentFile    = "content"
+	screenshotFile = "screenshot.webp"
+	// tileFile is the name pattern of the screenshot tiles, numbered from the top
+	tileFile = "screenshot-%d.webp"
+)
+
+// ErrNotArchived is returned if the archive holds no snapshot of a URL.
+var ErrNotArchived = errors.New("page not archived")
+
+// Snapshot is an archived page.
+type Snapshot struct {
+	// URL is the navigated URL, FinalURL the URL after redirects.
+	URL       string    `json:"url"`
+	FinalURL  string    `json:"final_url"`
+	Timestamp time.Time `json:"timestamp"`
+	Format    Format    `json:"format"`
+	// Dir is the directory of the payload files, relative to the archive.
+	Dir string `json:"dir"`
+	// Consent is the name of the consent rule whose banner was handled, empty if there was none.
+	Consent string `json:"consent,omitempty"`
+	// TileCount is the number of archived screenshot tiles.
+	TileCount int `json:"tile_count,omitempty"`
+
+	HTML       string `json:"-"`
+	Content    string `json:"-"`
+	Screenshot []byte `json:"-"`
+	// Tiles are the screenshot tiles of the page, like GetScreenshotTiles takes them.
+	Tiles [][]byte `json:"-"`
+}
+
+// Archive stores page snapshots on disk. Like a WARC file it has an append-only
+// index of records, one JSON line per snapshot, pointing to the payload files.
+// Later snapshots of a URL replace earlier ones.
+type Archive struct {
+	dir     string
+	mu      sync.Mutex
+	records map[string]Snapshot
+}
+
+// OpenArchive opens the archive in the directory, creating it if needed.
+func OpenArchive(dir string) (*Archive, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	a := &Archive{
+		dir:     dir,
+		records: make(map[string]Snapshot),
+	}
+
+	file, err := os.Open(filepath.Join(dir, archiveIndex))
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive index: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Snapshot
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal archive record: %w", err)
+		}
+		a.add(record)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive index: %w", err)
+	}
+
+	return a, nil
+}
+
+// Save writes the payloads of the snapshot and appends its record to the index.
+func (a *Archive) Save(s Snapshot) error {
+	if s.Timestamp.IsZero() {
+		s.Timestamp = time.Now()
+	}
+	hash := sha256.Sum256([]byte(s.URL + s.Timestamp.String()))
+	s.Dir = hex.EncodeToString(hash[:8])
+
+	dir := filepath.Join(a.dir, s.Dir)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	files := map[string][]byte{
+		htmlFile:    []byte(s.HTML),
+		contentFile: []byte(s.Content),
+	}
+	if len(s.Screenshot) > 0 {
+		files[screenshotFile] = s.Screenshot
+	}
+	s.TileCount = len(s.Tiles)
+	for i, tile := range s.Tiles {
+		files[fmt.Sprintf(tileFile, i+1)] = tile
+	}
+	for name, data := range files {
+		err = os.WriteFile(filepath.Join(dir, name), data, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	record, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive record: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(a.dir, archiveIndex), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive index: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(record, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write archive record: %w", err)
+	}
+	a.add(s)
+
+	return nil
+}
+
+// Get loads the latest snapshot of the URL, which may be the navigated or the final URL.
+func (a *Archive) Get(url string) (Snapshot, error) {
+	a.mu.Lock()
+	s, ok := a.records[archiveKey(url)]
+	a.mu.Unlock()
+	if !ok {
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrNotArchived, url)
+	}
+
+	dir := filepath.Join(a.dir, s.Dir)
+	html, err := os.ReadFile(filepath.Join(dir, htmlFile))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read archived html: %w", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, contentFile))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to read archived content: %w", err)
+	}
+	screenshot, err := os.ReadFile(filepath.Join(dir, screenshotFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, fmt.Errorf("failed to read archived screenshot: %w", err)
+	}
+
+	s.Tiles = nil
+	for i := range s.TileCount {
+		tile, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf(tileFile, i+1)))
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to read archived screenshot tile: %w", err)
+		}
+		s.Tiles = append(s.Tiles, tile)
+	}
+
+	s.HTML = string(html)
+	s.Content = string(content)
+	s.Screenshot = screenshot
+
+	return s, nil
+}
+
+func (a *Archive) add(s Snapshot) {
+	a.records[archiveKey(s.URL)] = s
+	if s.FinalURL != "" {
+		a.records[archiveKey(s.FinalURL)] = s
+	}
+}
+
+func archiveKey(url string) string {
+	return strings.TrimSuffix(strings.TrimSpace(url), "/")
+}
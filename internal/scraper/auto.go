@@ -0,0 +1,281 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// minTextLength is the length of visible text a fetched page needs to not look rendered by JavaScript.
+const minTextLength = 200
+
+// mountPoints are the ids of the elements single page application frameworks render into.
+var mountPoints = []string{"root", "app", "__next", "__nuxt", "___gatsby", "svelte"}
+
+// AutoBrowser fetches pages with plain HTTP requests and falls back to Chrome for pages
+// which look rendered by JavaScript, fail to fetch or need interactions.
+// Chrome is launched with the first fallback.
+type AutoBrowser struct {
+	log    *zap.Logger
+	http   *HTTPBrowser
+	launch func() (*Scraper, error)
+
+	mu     sync.Mutex
+	chrome *Scraper
+}
+
+// NewAutoBrowser creates a browser fetching pages with plain HTTP requests and Chrome if needed.
+func NewAutoBrowser(log *zap.Logger, format Format, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret string, opts ...Option) (*AutoBrowser, error) {
+	settings, err := newSettings(format, proxyServer, proxyUsername, proxyPassword, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	httpBrowser, err := newHTTPBrowser(log, settings, proxyServer, proxyUsername, proxyPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AutoBrowser{
+		log:  log,
+		http: httpBrowser,
+		launch: func() (*Scraper, error) {
+			return newScraper(log, settings, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret)
+		},
+	}, nil
+}
+
+func (b *AutoBrowser) CreatePage() (ScraperPage, error) {
+	return &autoPage{
+		browser: b,
+		http:    b.http.newPage(),
+	}, nil
+}
+
+// chromePage creates a page of Chrome, launching it if needed.
+func (b *AutoBrowser) chromePage() (ScraperPage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.chrome == nil {
+		b.log.Info("launching browser for javascript rendered pages")
+		chrome, err := b.launch()
+		if err != nil {
+			return nil, err
+		}
+		b.chrome = chrome
+	}
+
+	return b.chrome.CreatePage()
+}
+
+// Recover relaunches Chrome if it was launched and its connection died.
+func (b *AutoBrowser) Recover() (bool, error) {
+	b.mu.Lock()
+	chrome := b.chrome
+	b.mu.Unlock()
+	if chrome == nil {
+		return false, nil
+	}
+
+	return chrome.Recover()
+}
+
+// Close closes Chrome if it was launched.
+func (b *AutoBrowser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.chrome == nil {
+		return nil
+	}
+
+	return b.chrome.Close()
+}
+
+type autoPage struct {
+	browser *AutoBrowser
+	http    *httpPage
+	chrome  ScraperPage
+	// rendered is set if the current page was loaded by Chrome
+	rendered bool
+}
+
+// Navigate fetches the URL and loads it with Chrome if it looks rendered by JavaScript or the fetch failed.
+// Pages of websites with a login recipe are always loaded with Chrome, which logs in.
+func (p *autoPage) Navigate(ctx context.Context, url string) error {
+	p.rendered = false
+	if _, ok := p.browser.http.loginRecipe(url); ok {
+		return p.render(ctx, url)
+	}
+	err := p.http.Navigate(ctx, url)
+	if errors.Is(err, ErrDisallowed) {
+		return err
+	}
+	if err == nil && !looksJSRendered(p.http.html) {
+		return nil
+	}
+
+	p.browser.log.Info("falling back to browser", zap.String("url", url), zap.Error(err))
+
+	return p.render(ctx, url)
+}
+
+// render loads the URL with Chrome.
+func (p *autoPage) render(ctx context.Context, url string) error {
+	if p.chrome == nil {
+		page, err := p.browser.chromePage()
+		if err != nil {
+			return fmt.Errorf("failed to create browser page: %w", err)
+		}
+		p.chrome = page
+	}
+
+	err := p.chrome.Navigate(ctx, url)
+	if err != nil {
+		return err
+	}
+	p.rendered = true
+
+	return nil
+}
+
+// rendering returns the Chrome page with the current page, loading it if it was fetched.
+func (p *autoPage) rendering() (ScraperPage, error) {
+	if !p.rendered {
+		if p.http.url == "" {
+			return nil, errors.New("no page navigated")
+		}
+
+		// the interactions of a page have no context, the navigation is bounded by the browser timeout
+		err := p.render(context.Background(), p.http.url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p.chrome, nil
+}
+
+func (p *autoPage) PageContent() (string, int, int, error) {
+	if p.rendered {
+		return p.chrome.PageContent()
+	}
+
+	return p.http.PageContent()
+}
+
+func (p *autoPage) ClickButton(selector string) error {
+	page, err := p.rendering()
+	if err != nil {
+		return err
+	}
+
+	return page.ClickButton(selector)
+}
+
+func (p *autoPage) EnterInput(selector, input string) error {
+	page, err := p.rendering()
+	if err != nil {
+		return err
+	}
+
+	return page.EnterInput(selector, input)
+}
+
+func (p *autoPage) GetScreenshot() ([]byte, error) {
+	page, err := p.rendering()
+	if err != nil {
+		return nil, err
+	}
+
+	return page.GetScreenshot()
+}
+
+func (p *autoPage) GetScreenshotTiles() ([][]byte, error) {
+	page, err := p.rendering()
+	if err != nil {
+		return nil, err
+	}
+
+	return page.GetScreenshotTiles()
+}
+
+func (p *autoPage) Metadata() Metadata {
+	if p.rendered {
+		return p.chrome.Metadata()
+	}
+
+	return p.http.Metadata()
+}
+
+// Close closes the Chrome page if there is one.
+func (p *autoPage) Close() error {
+	closer, ok := p.chrome.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
+
+// looksJSRendered reports whether the html needs JavaScript to show its content:
+// its body has hardly any visible text or an empty mount point of a single page application.
+func looksJSRendered(page string) bool {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return true
+	}
+	body := findBodyNode(doc)
+	if body == nil {
+		return true
+	}
+
+	return visibleTextLength(body) < minTextLength || hasEmptyMountPoint(body)
+}
+
+func visibleTextLength(n *html.Node) int {
+	if n.Type == html.TextNode {
+		return len(strings.TrimSpace(n.Data))
+	}
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "script", "style", "noscript", "template":
+			return 0
+		}
+	}
+
+	length := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		length += visibleTextLength(c)
+	}
+
+	return length
+}
+
+func hasEmptyMountPoint(n *html.Node) bool {
+	if n.Type == html.ElementNode {
+		id := attribute(n, "id")
+		isMountPoint := n.Data == "app-root"
+		for _, mountPoint := range mountPoints {
+			isMountPoint = isMountPoint || id == mountPoint
+		}
+		if isMountPoint && visibleTextLength(n) == 0 {
+			return true
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if hasEmptyMountPoint(c) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,28 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLooksJSRendered(t *testing.T) {
+	text := "<p>" + strings.Repeat("Die Gruft ist unser gruseligster Raum. ", 10) + "</p>"
+	tests := []struct {
+		name string
+		page string
+		want bool
+	}{
+		{name: "static page", page: "<html><body><h1>Escape Rooms</h1>" + text + "</body></html>", want: false},
+		{name: "rendered mount point", page: `<html><body><div id="__next">` + text + `</div></body></html>`, want: false},
+		{name: "empty body", page: `<html><body><script src="/app.js"></script></body></html>`, want: true},
+		{name: "noscript only", page: `<html><body><noscript>` + text + `</noscript></body></html>`, want: true},
+		{name: "empty mount point", page: `<html><body><div id="root"></div><footer>` + text + `</footer></body></html>`, want: true},
+		{name: "angular", page: `<html><body><app-root></app-root>` + text + `</body></html>`, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := looksJSRendered(tt.page); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/launcher/flags"
+	"github.com/go-rod/rod/lib/proto"
+	"go.uber.org/zap"
+)
+
+// chrome is a launched Chrome process which can be relaunched after a crash.
+type chrome struct {
+	log           *zap.Logger
+	proxyServer   string
+	proxyUsername string
+	proxyPassword string
+
+	mu       sync.Mutex
+	browser  *rod.Browser
+	launcher *launcher.Launcher
+}
+
+func launchChrome(log *zap.Logger, proxyServer, proxyUsername, proxyPassword string) (*chrome, error) {
+	c := &chrome{
+		log:           log,
+		proxyServer:   proxyServer,
+		proxyUsername: proxyUsername,
+		proxyPassword: proxyPassword,
+	}
+
+	err := c.launch()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *chrome) current() *rod.Browser {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.browser
+}
+
+// browserError marks errors of a lost connection to Chrome with ErrBrowserUnreachable.
+func browserError(err error) error {
+	var opErr *net.OpError
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.As(err, &opErr) {
+		return fmt.Errorf("%w: %w", ErrBrowserUnreachable, err)
+	}
+
+	return err
+}
+
+// alive reports whether Chrome still answers over CDP.
+func (c *chrome) alive() bool {
+	_, err := proto.BrowserGetVersion{}.Call(c.current().Timeout(5 * time.Second))
+
+	return err == nil
+}
+
+func (c *chrome) relaunch() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shutdown()
+
+	return c.launch()
+}
+
+func (c *chrome) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.shutdown()
+}
+
+// shutdown closes the browser and removes the process and its user data directory.
+func (c *chrome) shutdown() error {
+	err := c.browser.Close()
+	c.launcher.Kill()
+	c.launcher.Cleanup()
+	if err != nil {
+		return fmt.Errorf("failed to close browser: %w", err)
+	}
+
+	return nil
+}
+
+func (c *chrome) launch() error {
+	c.log.Info("creating new browser")
+	path, _ := launcher.LookPath()
+	l := launcher.
+		New().
+		Headless(true).
+		Bin(path).
+		NoSandbox(true)
+
+	withProxy := c.proxyServer != "" && c.proxyUsername != "" && c.proxyPassword != ""
+	if withProxy {
+		l.Set(flags.ProxyServer, c.proxyServer)
+	}
+
+	c.log.With(zap.Any("launcher-flags", l.Flags)).Info("launching browser")
+	u, err := l.Launch()
+	if err != nil {
+		return fmt.Errorf("failed to launch new browser: %w", err)
+	}
+
+	c.log.Info("connecting to browser")
+	browser := rod.New().ControlURL(u)
+	err = browser.Connect()
+	if err != nil {
+		l.Kill()
+
+		return fmt.Errorf("failed to connect to browser: %w", err)
+	}
+
+	if withProxy {
+		c.log.Info("setting up auth handler")
+		go func() {
+			err := browser.HandleAuth(c.proxyUsername, c.proxyPassword)()
+			if err != nil {
+				c.log.Error("failed to handle auth for proxy", zap.Error(err))
+			}
+		}()
+	}
+
+	c.browser = browser
+	c.launcher = l
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SplitContent splits page content of the given format into chunks within the limit.
+// HTML is split at DOM boundaries, markdown and text at line breaks.
+func SplitContent(content string, format Format, limit int, size func(string) int) ([]string, error) {
+	if format == FormatMarkdown || format == FormatText {
+		return SplitText(content, limit, size), nil
+	}
+
+	return SplitHTML(content, limit, size)
+}
+
+// SplitText splits text at line breaks into chunks whose size stays within the limit.
+// Lines which are too large on their own are split into words.
+func SplitText(content string, limit int, size func(string) int) []string {
+	s := splitter{
+		limit: limit,
+		size:  size,
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineSize := s.size(line)
+		if s.used+lineSize > s.limit {
+			s.flush()
+		}
+		if lineSize > s.limit {
+			s.addWords(line)
+
+			continue
+		}
+		s.append(line, lineSize)
+	}
+	s.flush()
+
+	return s.chunks
+}
+
+// SplitHTML splits cleaned page content at DOM boundaries into chunks whose size,
+// measured by the size function (e.g. a token estimator), stays within the limit.
+// Consecutive siblings are packed into the same chunk; elements which are too
+// large on their own are split into their children and text into words.
+func SplitHTML(content string, limit int, size func(string) int) ([]string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	s := splitter{
+		limit: limit,
+		size:  size,
+	}
+	for _, n := range nodes {
+		err = s.add(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.flush()
+
+	return s.chunks, nil
+}
+
+type splitter struct {
+	limit   int
+	size    func(string) int
+	chunks  []string
+	current strings.Builder
+	used    int
+}
+
+func (s *splitter) add(n *html.Node) error {
+	var buf bytes.Buffer
+	err := html.Render(&buf, n)
+	if err != nil {
+		return fmt.Errorf("failed to render html: %w", err)
+	}
+
+	rendered := buf.String()
+	size := s.size(rendered)
+	if s.used+size <= s.limit {
+		s.append(rendered, size)
+
+		return nil
+	}
+
+	s.flush()
+	if size <= s.limit {
+		s.append(rendered, size)
+
+		return nil
+	}
+
+	if n.Type == html.TextNode || n.FirstChild == nil {
+		s.addWords(rendered)
+
+		return nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		err = s.add(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addWords splits a text which does not fit into a single chunk at whitespace.
+func (s *splitter) addWords(text string) {
+	for _, word := range strings.Fields(text) {
+		size := s.size(word + " ")
+		if s.used+size > s.limit {
+			s.flush()
+		}
+		s.append(word+" ", size)
+	}
+}
+
+func (s *splitter) append(text string, size int) {
+	s.current.WriteString(text)
+	s.used += size
+}
+
+func (s *splitter) flush() {
+	if s.current.Len() == 0 {
+		return
+	}
+
+	s.chunks = append(s.chunks, s.current.String())
+	s.current.Reset()
+	s.used = 0
+}
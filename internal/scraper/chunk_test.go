@@ -0,0 +1,29 @@
+package scraper_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+)
+
+func TestSplitHTML(t *testing.T) {
+	room := "<div><h2>Escape Room</h2><p>Horror</p></div>"
+	content := strings.Repeat(room, 10)
+
+	chunks, err := scraper.SplitHTML(content, 3*len(room), func(s string) int { return len(s) })
+	if err != nil {
+		t.Fatalf("Error splitting html: %v", err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", len(chunks))
+	}
+	if strings.Join(chunks, "") != content {
+		t.Error("Expected the chunks to contain the whole content")
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 3*len(room) {
+			t.Errorf("Expected chunk within the limit, got %d", len(chunk))
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package scraper
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+//go:embed consent.json
+var defaultConsentRules []byte
+
+// ConsentRule describes the overlay of a consent manager.
+// Detect and Remove selectors are compound CSS selectors without combinators, e.g. #id, .class,
+// tag[attr^=value], so plain HTML can be matched without a browser. Dismiss selectors are
+// only used in the browser and may be any CSS selector; "host >>> selector" queries the shadow root of host.
+type ConsentRule struct {
+	Name string `json:"name"`
+	// Detect matches elements present if the overlay is shown.
+	Detect []string `json:"detect"`
+	// Dismiss matches the buttons closing the overlay, the first present one is clicked.
+	Dismiss []string `json:"dismiss"`
+	// Remove matches the elements of the overlay which are stripped from the page.
+	Remove []string `json:"remove"`
+}
+
+// DefaultConsentRules returns the rules of common consent managers: Borlabs, Cookiebot, OneTrust and Usercentrics.
+func DefaultConsentRules() []ConsentRule {
+	rules, err := parseConsentRules(defaultConsentRules)
+	if err != nil {
+		panic(err)
+	}
+
+	return rules
+}
+
+// LoadConsentRules reads a JSON file of consent rules.
+func LoadConsentRules(filename string) ([]ConsentRule, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consent rules: %w", err)
+	}
+
+	return parseConsentRules(data)
+}
+
+func parseConsentRules(data []byte) ([]ConsentRule, error) {
+	var rules []ConsentRule
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal consent rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		for _, selector := range append(append([]string{}, rule.Detect...), rule.Remove...) {
+			_, err = parseSelector(selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector of consent rule %s: %w", rule.Name, err)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// consentScript dismisses and removes the overlay of a rule in the browser. It returns whether the overlay was
+// found and whether a dismiss button was clicked.
+const consentScript = `(detect, dismiss, remove) => {
+	const query = (selector, all) => {
+		const parts = selector.split(" >>> ");
+		let root = document;
+		for (const part of parts.slice(0, -1)) {
+			root = root.querySelector(part)?.shadowRoot;
+			if (!root) return all ? [] : null;
+		}
+		const last = parts[parts.length - 1];
+		return all ? Array.from(root.querySelectorAll(last)) : root.querySelector(last);
+	};
+	if (!detect.some((selector) => query(selector, false))) {
+		return { found: false, clicked: false };
+	}
+	let clicked = false;
+	for (const selector of dismiss) {
+		const button = query(selector, false);
+		if (button) {
+			button.click();
+			clicked = true;
+			break;
+		}
+	}
+	for (const selector of remove) {
+		query(selector, true).forEach((element) => element.remove());
+	}
+	document.documentElement.style.overflow = "";
+	document.body.style.overflow = "";
+	return { found: true, clicked: clicked };
+}`
+
+// stripConsent removes the overlays of the rules from the html. It returns the html
+// and the name of the first rule whose overlay was found, empty if there was none.
+func stripConsent(page string, rules []ConsentRule) (string, string, error) {
+	if len(rules) == 0 {
+		return page, "", nil
+	}
+
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	handled := ""
+	for _, rule := range rules {
+		if !matchesAny(doc, rule.Detect) {
+			continue
+		}
+		if handled == "" {
+			handled = rule.Name
+		}
+
+		for _, selector := range rule.Remove {
+			sel, _ := parseSelector(selector)
+			removeMatching(doc, sel)
+		}
+	}
+	if handled == "" {
+		return page, "", nil
+	}
+
+	var buf bytes.Buffer
+	err = html.Render(&buf, doc)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render html: %w", err)
+	}
+
+	return buf.String(), handled, nil
+}
+
+func matchesAny(doc *html.Node, selectors []string) bool {
+	for _, selector := range selectors {
+		sel, _ := parseSelector(selector)
+		if findMatching(doc, sel) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findMatching(n *html.Node, sel selector) *html.Node {
+	if sel.matches(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findMatching(c, sel); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+func removeMatching(n *html.Node, sel selector) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if sel.matches(c) {
+			n.RemoveChild(c)
+		} else {
+			removeMatching(c, sel)
+		}
+		c = next
+	}
+}
+
+// selector is a compound CSS selector: an optional tag followed by ids, classes and attribute conditions.
+type selector struct {
+	tag        string
+	conditions []attributeCondition
+}
+
+type attributeCondition struct {
+	key string
+	// op is "" for presence, "=", "^=", "$=", "*=" or "~=" for the class list
+	op    string
+	value string
+}
+
+func (s selector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (s.tag != "" && s.tag != n.Data) {
+		return false
+	}
+
+	for _, c := range s.conditions {
+		value, ok := attributeValue(n, c.key)
+		if !ok {
+			return false
+		}
+
+		var match bool
+		switch c.op {
+		case "":
+			match = true
+		case "=":
+			match = value == c.value
+		case "^=":
+			match = strings.HasPrefix(value, c.value)
+		case "$=":
+			match = strings.HasSuffix(value, c.value)
+		case "*=":
+			match = strings.Contains(value, c.value)
+		case "~=":
+			match = containsField(value, c.value)
+		}
+		if !match {
+			return false
+		}
+	}
+
+	return true
+}
+
+func attributeValue(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+
+	return "", false
+}
+
+func containsField(value, field string) bool {
+	for _, f := range strings.Fields(value) {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSelector parses a compound selector like div#id.class[attr^="value"].
+func parseSelector(s string) (selector, error) {
+	sel := selector{}
+	rest := strings.TrimSpace(s)
+	if rest == "" || strings.ContainsAny(outsideBrackets(rest), " >+~,:") {
+		return sel, fmt.Errorf("unsupported selector %q", s)
+	}
+
+	end := strings.IndexAny(rest, "#.[")
+	if end < 0 {
+		end = len(rest)
+	}
+	sel.tag = strings.ToLower(rest[:end])
+	rest = rest[end:]
+
+	for rest != "" {
+		switch rest[0] {
+		case '#', '.':
+			end := strings.IndexAny(rest[1:], "#.[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return sel, fmt.Errorf("unsupported selector %q", s)
+			}
+			if rest[0] == '#' {
+				sel.conditions = append(sel.conditions, attributeCondition{key: "id", op: "=", value: name})
+			} else {
+				sel.conditions = append(sel.conditions, attributeCondition{key: "class", op: "~=", value: name})
+			}
+			rest = rest[end+1:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return sel, fmt.Errorf("unsupported selector %q", s)
+			}
+			sel.conditions = append(sel.conditions, parseAttributeCondition(rest[1:end]))
+			rest = rest[end+1:]
+		default:
+			return sel, fmt.Errorf("unsupported selector %q", s)
+		}
+	}
+
+	return sel, nil
+}
+
+// outsideBrackets returns the selector without its attribute conditions.
+func outsideBrackets(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func parseAttributeCondition(s string) attributeCondition {
+	for _, op := range []string{"^=", "$=", "*=", "~=", "="} {
+		key, value, ok := strings.Cut(s, op)
+		if ok {
+			return attributeCondition{
+				key:   strings.TrimSpace(key),
+				op:    op,
+				value: strings.Trim(strings.TrimSpace(value), `"'`),
+			}
+		}
+	}
+
+	return attributeCondition{key: strings.TrimSpace(s)}
+}
@@ -0,0 +1,63 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripConsent(t *testing.T) {
+	rules := DefaultConsentRules()
+	tests := []struct {
+		name    string
+		page    string
+		consent string
+	}{
+		{name: "no banner", page: `<html><body><h1>Die Gruft</h1></body></html>`},
+		{
+			name:    "borlabs",
+			page:    `<html><body><div id="BorlabsCookieBox"><p>Wir nutzen Cookies</p><a data-cookie-accept>OK</a></div><h1>Die Gruft</h1></body></html>`,
+			consent: "borlabs",
+		},
+		{
+			name:    "cookiebot with underlay",
+			page:    `<html><body><div id="CybotCookiebotDialog">Cookies</div><div id="CybotCookiebotDialogBodyUnderlay"></div><h1>Die Gruft</h1></body></html>`,
+			consent: "cookiebot",
+		},
+	}
+
+	for _, tt := range tests {
+		page, consent, err := stripConsent(tt.page, rules)
+		if err != nil {
+			t.Fatalf("%s: error stripping consent: %v", tt.name, err)
+		}
+		if consent != tt.consent {
+			t.Errorf("%s: expected consent %q, got %q", tt.name, tt.consent, consent)
+		}
+		if strings.Contains(page, "Cookie") || !strings.Contains(page, "<h1>Die Gruft</h1>") {
+			t.Errorf("%s: expected only the banner to be stripped, got %s", tt.name, page)
+		}
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		valid    bool
+	}{
+		{selector: "#BorlabsCookieBox", valid: true},
+		{selector: "div.cookie-banner.visible", valid: true},
+		{selector: `[data-testid="uc banner"]`, valid: true},
+		{selector: "[class^=cmp-]", valid: true},
+		{selector: "#banner button", valid: false},
+		{selector: "div > .accept", valid: false},
+		{selector: "a:first-child", valid: false},
+		{selector: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		_, err := parseSelector(tt.selector)
+		if (err == nil) != tt.valid {
+			t.Errorf("%q: expected valid %t, got error %v", tt.selector, tt.valid, err)
+		}
+	}
+}
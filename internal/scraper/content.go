@@ -0,0 +1,86 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Format is the format the page content is rendered in for the llm.
+type Format string
+
+const (
+	// FormatHTML renders the cleaned html of the body.
+	FormatHTML Format = "html"
+	// FormatMarkdown renders headings, lists, links and images as markdown.
+	FormatMarkdown Format = "markdown"
+	// FormatText renders the text only, links are kept behind their text.
+	FormatText Format = "text"
+)
+
+// ParseFormat parses the name of a content format.
+func ParseFormat(format string) (Format, error) {
+	switch f := Format(format); f {
+	case FormatHTML, FormatMarkdown, FormatText:
+		return f, nil
+	case "":
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("unknown content format: %s", format)
+	}
+}
+
+// cleanHTML parses the page and returns its body without scripts, styles,
+// comments, unimportant attributes and empty elements. All links and images
+// are resolved to absolute URLs against the page URL or the <base> of the page.
+func cleanHTML(page, pageURL string) (*html.Node, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	// Find the <body> node
+	bodyNode := findBodyNode(doc)
+	if bodyNode == nil {
+		return nil, errors.New("failed to find body node")
+	}
+
+	resolveURLs(bodyNode, documentBase(doc, pageURL))
+
+	// Clean up the HTML
+	removeUnwantedTags(bodyNode, "script")
+	removeUnwantedTags(bodyNode, "noscript")
+	removeUnwantedTags(bodyNode, "style")
+	removeUnwantedTags(bodyNode, "iframe")
+	normalizeWhitespace(bodyNode)
+	removeComments(bodyNode)
+	removeAllAttributesExceptImportant(bodyNode)
+	removeEmptyLinks(bodyNode)
+	removeEmptyElements(bodyNode)
+
+	return bodyNode, nil
+}
+
+// RenderContent cleans the raw html of a page and renders it in the given format.
+// Relative URLs are resolved against the page URL.
+func RenderContent(page, pageURL string, format Format) (string, error) {
+	bodyNode, err := cleanHTML(page, pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case FormatMarkdown, FormatText:
+		return renderText(bodyNode, format == FormatMarkdown), nil
+	default:
+		var buf bytes.Buffer
+		if err := html.Render(&buf, bodyNode); err != nil {
+			return "", fmt.Errorf("failed to render html: %w", err)
+		}
+
+		return buf.String(), nil
+	}
+}
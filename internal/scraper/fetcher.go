@@ -0,0 +1,162 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxPageSize is the part of a response which is read by the HTTP browser.
+const maxPageSize = 10 * 1024 * 1024
+
+// ErrHTTPInteraction is returned by pages of the HTTP browser for interactions which need a live browser.
+var ErrHTTPInteraction = errors.New("interactions are not supported by plain HTTP pages")
+
+// HTTPBrowser fetches pages with plain HTTP requests instead of a headless browser.
+// It is much lighter than Chrome, but it doesn't run JavaScript, see AutoBrowser.
+type HTTPBrowser struct {
+	*settings
+	log    *zap.Logger
+	client *http.Client
+}
+
+// NewHTTPBrowser creates a browser fetching pages with plain HTTP requests in the format.
+func NewHTTPBrowser(log *zap.Logger, format Format, proxyServer, proxyUsername, proxyPassword string, opts ...Option) (*HTTPBrowser, error) {
+	settings, err := newSettings(format, proxyServer, proxyUsername, proxyPassword, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHTTPBrowser(log, settings, proxyServer, proxyUsername, proxyPassword)
+}
+
+func newHTTPBrowser(log *zap.Logger, settings *settings, proxyServer, proxyUsername, proxyPassword string) (*HTTPBrowser, error) {
+	client, err := proxyClient(proxyServer, proxyUsername, proxyPassword)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = 30 * time.Second
+
+	return &HTTPBrowser{
+		settings: settings,
+		log:      log,
+		client:   client,
+	}, nil
+}
+
+func (b *HTTPBrowser) CreatePage() (ScraperPage, error) {
+	return b.newPage(), nil
+}
+
+func (b *HTTPBrowser) newPage() *httpPage {
+	return &httpPage{browser: b}
+}
+
+type httpPage struct {
+	browser *HTTPBrowser
+	// url is the navigated URL, finalURL the URL after redirects
+	url      string
+	finalURL string
+	html     string
+	// consent is the name of the consent rule whose banner was stripped
+	consent string
+}
+
+func (p *httpPage) ClickButton(selector string) error {
+	return fmt.Errorf("failed to click %s: %w", selector, ErrHTTPInteraction)
+}
+
+func (p *httpPage) EnterInput(selector, _ string) error {
+	return fmt.Errorf("failed to input into %s: %w", selector, ErrHTTPInteraction)
+}
+
+// Navigate fetches the URL if the robots.txt of the website allows it, waiting for the politeness limits of its host.
+func (p *httpPage) Navigate(ctx context.Context, url string) error {
+	b := p.browser
+	p.url = url
+	p.finalURL = ""
+	p.html = ""
+	p.consent = ""
+
+	release, err := b.admit(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", b.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to navigate to url: %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("failed to navigate to url: %s: status %s", url, resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return fmt.Errorf("failed to navigate to url: %s: no html but %s", url, contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return fmt.Errorf("failed to read page: %w", err)
+	}
+	p.finalURL = resp.Request.URL.String()
+	p.html, p.consent, err = stripConsent(string(body), b.consentRules)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PageContent returns the cleaned content of the page in the format of the browser,
+// the length of the raw html and the length of the rendered content.
+func (p *httpPage) PageContent() (string, int, int, error) {
+	if p.finalURL == "" {
+		return "", 0, 0, errors.New("no page navigated")
+	}
+
+	content, err := RenderContent(p.html, p.finalURL, p.browser.format)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	p.browser.saveSnapshot(p.browser.log, Snapshot{
+		URL:      p.url,
+		FinalURL: p.finalURL,
+		HTML:     p.html,
+		Content:  content,
+		Consent:  p.consent,
+	})
+
+	return content, len(p.html), len(content), nil
+}
+
+func (p *httpPage) GetScreenshotTiles() ([][]byte, error) {
+	return nil, fmt.Errorf("failed to take screenshot: %w", ErrHTTPInteraction)
+}
+
+// Metadata describes the page of the last navigation. Consent banners can only be stripped, not dismissed.
+func (p *httpPage) Metadata() Metadata {
+	return Metadata{
+		URL:     p.url,
+		Consent: p.consent,
+	}
+}
+
+func (p *httpPage) GetScreenshot() ([]byte, error) {
+	return nil, fmt.Errorf("failed to take screenshot: %w", ErrHTTPInteraction)
+}
@@ -0,0 +1,67 @@
+package scraper_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"go.uber.org/zap"
+)
+
+func TestHTTPBrowser(t *testing.T) {
+	var userAgent string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /intern\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/rooms/", http.StatusFound)
+	})
+	mux.HandleFunc("/rooms/", func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><script>track()</script></head><body><h1 class="title">Die Gruft</h1><a href="gruft">Mehr</a></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	browser, err := scraper.NewHTTPBrowser(zap.NewNop(), scraper.FormatMarkdown, "", "", "", scraper.WithUserAgent("testbot/1.0"), scraper.WithPoliteness(0, 1))
+	if err != nil {
+		t.Fatalf("Error creating browser: %v", err)
+	}
+	page, err := browser.CreatePage()
+	if err != nil {
+		t.Fatalf("Error creating page: %v", err)
+	}
+
+	err = page.Navigate(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("Error navigating: %v", err)
+	}
+	content, _, _, err := page.PageContent()
+	if err != nil {
+		t.Fatalf("Error getting content: %v", err)
+	}
+	// links are resolved against the redirected URL
+	want := "# Die Gruft\n\n[Mehr](" + server.URL + "/rooms/gruft)"
+	if strings.TrimSpace(content) != want {
+		t.Errorf("Expected content %q, got %q", want, content)
+	}
+	if userAgent != "testbot/1.0" {
+		t.Errorf("Expected the configured user agent, got %q", userAgent)
+	}
+
+	err = page.Navigate(context.Background(), server.URL+"/intern/preise")
+	if !errors.Is(err, scraper.ErrDisallowed) {
+		t.Errorf("Expected the page to be disallowed, got %v", err)
+	}
+	err = page.ClickButton("button")
+	if !errors.Is(err, scraper.ErrHTTPInteraction) {
+		t.Errorf("Expected interactions to fail, got %v", err)
+	}
+}
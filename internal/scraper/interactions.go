@@ -1,24 +1,30 @@
 package scraper
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/go-rod/rod/lib/proto"
-	"golang.org/x/net/html"
+	"go.uber.org/zap"
+)
+
+const (
+	// screenshotTileHeight is the height of a screenshot tile in CSS pixels.
+	screenshotTileHeight = 1600
+	// maxScreenshotTiles bounds the tiles of a page, each costs as many tokens as a page of text.
+	maxScreenshotTiles = 6
 )
 
 func (s *Scraper) ClickButton(selector string) error {
 	el, err := s.getPage().Element(selector)
 	if err != nil {
-		return fmt.Errorf("failed to find element: %w", err)
+		return fmt.Errorf("failed to find element: %w", browserError(err))
 	}
 	err = el.Click(proto.InputMouseButtonLeft, 1)
 	if err != nil {
-		return fmt.Errorf("failed to click element: %w", err)
+		return fmt.Errorf("failed to click element: %w", browserError(err))
 	}
 
 	return nil
@@ -27,65 +33,132 @@ func (s *Scraper) ClickButton(selector string) error {
 func (s *Scraper) EnterInput(selector, input string) error {
 	el, err := s.getPage().Element(selector)
 	if err != nil {
-		return fmt.Errorf("failed to find element: %w", err)
+		return fmt.Errorf("failed to find element: %w", browserError(err))
 	}
 	err = el.Input(input)
 	if err != nil {
-		return fmt.Errorf("failed to input text: %w", err)
+		return fmt.Errorf("failed to input text: %w", browserError(err))
 	}
 
 	return nil
 }
 
+// PageContent returns the cleaned content of the page in the format of the scraper,
+// the length of the raw html and the length of the rendered content.
 func (s *Scraper) PageContent() (string, int, int, error) {
 	page, err := s.getPage().HTML()
 	if err != nil {
-		return "", 0, 0, fmt.Errorf("failed to get html: %w", err)
+		return "", 0, 0, fmt.Errorf("failed to get html: %w", browserError(err))
+	}
+
+	info, err := s.getPage().Info()
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("failed to get page info: %w", browserError(err))
 	}
 
-	doc, err := html.Parse(strings.NewReader(page))
+	content, err := RenderContent(page, info.URL, s.format)
 	if err != nil {
-		return "", 0, 0, fmt.Errorf("failed to parse html: %w", err)
+		return "", 0, 0, err
+	}
+
+	if s.archive != nil {
+		s.archivePage(page, content, info.URL)
 	}
 
-	// Find the <body> node
-	bodyNode := findBodyNode(doc)
-	if bodyNode == nil {
-		return "", 0, 0, errors.New("failed to find body node")
+	return content, len(page), len(content), nil
+}
+
+// archivePage saves a snapshot of the current page with its screenshot and screenshot tiles.
+// Failures are logged only, they don't affect the scrape.
+func (s *Scraper) archivePage(page, content, finalURL string) {
+	screenshot, err := s.GetScreenshot()
+	if err != nil {
+		s.log.Warn("failed to take screenshot for archive", zap.Error(err))
+	}
+	tiles, err := s.GetScreenshotTiles()
+	if err != nil {
+		s.log.Warn("failed to take screenshot tiles for archive", zap.Error(err))
 	}
 
-	// Clean up the HTML
-	removeUnwantedTags(bodyNode, "script")
-	removeUnwantedTags(bodyNode, "noscript")
-	removeUnwantedTags(bodyNode, "style")
-	removeUnwantedTags(bodyNode, "iframe")
-	normalizeWhitespace(bodyNode)
-	removeComments(bodyNode)
-	removeAllAttributesExceptImportant(bodyNode)
-	removeEmptyLinks(bodyNode)
-	removeEmptyElements(bodyNode)
+	s.saveSnapshot(s.log, Snapshot{
+		URL:        s.url,
+		FinalURL:   finalURL,
+		HTML:       page,
+		Content:    content,
+		Consent:    s.consent,
+		Screenshot: screenshot,
+		Tiles:      tiles,
+	})
+}
 
-	var buf bytes.Buffer
-	if err := html.Render(&buf, bodyNode); err != nil {
-		return "", 0, 0, fmt.Errorf("failed to render html: %w", err)
+// Navigate loads the URL if the robots.txt of the website allows it, waiting for the politeness limits of its host.
+// It logs into the website first if there is a login recipe for it. A consent banner is dismissed afterwards.
+func (s *Scraper) Navigate(ctx context.Context, url string) error {
+	s.url = url
+	err := s.login(ctx, url)
+	if err != nil {
+		return err
 	}
 
-	return buf.String(), len(page), len(buf.String()), nil
+	return s.load(ctx, url)
 }
 
-func (s *Scraper) Navigate(url string) error {
-	err := s.getPage().Navigate(url)
+// load navigates to the URL and handles its consent banner.
+func (s *Scraper) load(ctx context.Context, url string) error {
+	release, err := s.admit(ctx, url)
 	if err != nil {
-		return fmt.Errorf("failed to navigate to url: %s: %w", url, err)
+		return err
+	}
+	defer release()
+
+	return s.open(ctx, url)
+}
+
+// open navigates to the admitted URL and handles its consent banner.
+func (s *Scraper) open(ctx context.Context, url string) error {
+	s.consent = ""
+	s.consentDismissed = false
+	err := s.page.Context(ctx).Timeout(s.defaultBrowserTimeout).Navigate(url)
+	if err != nil {
+		return fmt.Errorf("failed to navigate to url: %s: %w", url, browserError(err))
 	}
 	err = s.getPage().WaitStable(time.Second)
 	if err != nil {
-		return fmt.Errorf("failed to wait for page to load: %w", err)
+		return fmt.Errorf("failed to wait for page to load: %w", browserError(err))
 	}
+	s.handleConsent()
 
 	return nil
 }
 
+// handleConsent clicks the dismiss button of the first consent banner found on the page and removes its overlay.
+// Failures are logged only, a banner left over doesn't hide the html of the page.
+func (s *Scraper) handleConsent() {
+	for _, rule := range s.consentRules {
+		obj, err := s.getPage().Eval(consentScript, rule.Detect, rule.Dismiss, rule.Remove)
+		if err != nil {
+			s.log.Warn("failed to handle consent banner", zap.String("rule", rule.Name), zap.Error(err))
+
+			continue
+		}
+		if !obj.Value.Get("found").Bool() {
+			continue
+		}
+
+		s.consent = rule.Name
+		s.consentDismissed = obj.Value.Get("clicked").Bool()
+		s.log.Info("handled consent banner", zap.String("rule", rule.Name), zap.Bool("dismissed", s.consentDismissed))
+		if s.consentDismissed {
+			err = s.getPage().WaitStable(time.Second)
+			if err != nil {
+				s.log.Warn("failed to wait for page after consent", zap.Error(err))
+			}
+		}
+
+		return
+	}
+}
+
 func (s *Scraper) GetScreenshot() ([]byte, error) {
 	byes, err := s.getPage().Screenshot(true, &proto.PageCaptureScreenshot{
 		Format:                proto.PageCaptureScreenshotFormatWebp,
@@ -93,12 +166,49 @@ func (s *Scraper) GetScreenshot() ([]byte, error) {
 		CaptureBeyondViewport: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+		return nil, fmt.Errorf("failed to take screenshot: %w", browserError(err))
 	}
 
 	return byes, nil
 }
 
+// GetScreenshotTiles captures the page in WebP tiles of the viewport width and up to screenshotTileHeight
+// CSS pixels height. Pages taller than maxScreenshotTiles tiles are cut.
+func (s *Scraper) GetScreenshotTiles() ([][]byte, error) {
+	page := s.getPage()
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get layout metrics: %w", browserError(err))
+	}
+	if metrics.CSSContentSize == nil || metrics.CSSLayoutViewport == nil {
+		return nil, errors.New("failed to get page size")
+	}
+
+	width := float64(metrics.CSSLayoutViewport.ClientWidth)
+	height := metrics.CSSContentSize.Height
+	tiles := [][]byte{}
+	for y := 0.0; y < height && len(tiles) < maxScreenshotTiles; y += screenshotTileHeight {
+		tile, err := page.Screenshot(false, &proto.PageCaptureScreenshot{
+			Format:  proto.PageCaptureScreenshotFormatWebp,
+			Quality: createPointer(50),
+			Clip: &proto.PageViewport{
+				X:      0,
+				Y:      y,
+				Width:  width,
+				Height: min(screenshotTileHeight, height-y),
+				Scale:  1,
+			},
+			CaptureBeyondViewport: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to take screenshot tile: %w", browserError(err))
+		}
+		tiles = append(tiles, tile)
+	}
+
+	return tiles, nil
+}
+
 func createPointer[A any](value A) *A {
 	return &value
 }
@@ -0,0 +1,336 @@
+package scraper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+	"go.uber.org/zap"
+)
+
+// ErrLoginFailed is returned if the login form is still shown after submitting the credentials.
+var ErrLoginFailed = errors.New("login failed")
+
+// LoginRecipe describes the login form of a website which needs authentication.
+// The selectors are CSS selectors of the form fields.
+type LoginRecipe struct {
+	// Host is the host of the pages which need the login, e.g. backoffice.example.com. Its subdomains need it too.
+	Host string `json:"host"`
+	// URL is the page of the login form, it may be on another host and is loaded regardless of its robots.txt.
+	URL      string `json:"url"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Submit   string `json:"submit"`
+	// OTP is the field of the one-time password, if the website asks for one after submitting the credentials.
+	OTP string `json:"otp,omitempty"`
+	// OTPSubmit is clicked after entering the one-time password, the form is submitted with enter if it is empty.
+	OTPSubmit string `json:"otp_submit,omitempty"`
+}
+
+// LoadLoginRecipes reads a JSON file of login recipes.
+func LoadLoginRecipes(filename string) ([]LoginRecipe, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read login recipes: %w", err)
+	}
+
+	var recipes []LoginRecipe
+	err = json.Unmarshal(data, &recipes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal login recipes: %w", err)
+	}
+
+	for _, recipe := range recipes {
+		if recipe.Host == "" || recipe.URL == "" || recipe.Email == "" || recipe.Password == "" || recipe.Submit == "" {
+			return nil, fmt.Errorf("login recipe of %q lacks the host, url or a selector of the credentials", recipe.Host)
+		}
+		u, err := url.Parse(recipe.URL)
+		if err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("login recipe of %q has no absolute url: %s", recipe.Host, recipe.URL)
+		}
+	}
+
+	return recipes, nil
+}
+
+// loginRecipe returns the recipe for the host of the URL or one of its parent domains, if there is one.
+func (s *settings) loginRecipe(rawURL string) (LoginRecipe, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return LoginRecipe{}, false
+	}
+
+	host := strings.ToLower(u.Host)
+	for _, recipe := range s.loginRecipes {
+		recipeHost := strings.ToLower(recipe.Host)
+		if host == recipeHost || strings.HasSuffix(host, "."+recipeHost) {
+			return recipe, true
+		}
+	}
+
+	return LoginRecipe{}, false
+}
+
+// sessions tracks the hosts Chrome is logged into. Cookies live as long as the Chrome process,
+// so a relaunched Chrome starts without sessions.
+type sessions struct {
+	// mu is held during logins, so pages wait for the login of another page to the same host
+	mu       sync.Mutex
+	loggedIn map[string]bool
+}
+
+func newSessions() *sessions {
+	return &sessions{loggedIn: make(map[string]bool)}
+}
+
+func (s *sessions) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loggedIn = make(map[string]bool)
+}
+
+// login logs into the website of the URL if there is a login recipe for its host and Chrome isn't logged in yet.
+// The session cookies are restored from and saved to the session directory.
+func (s *Scraper) login(ctx context.Context, rawURL string) error {
+	recipe, ok := s.loginRecipe(rawURL)
+	if !ok {
+		return nil
+	}
+
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+	if s.sessions.loggedIn[recipe.Host] {
+		return nil
+	}
+
+	err := s.restoreSession(recipe)
+	if err != nil {
+		s.log.Warn("failed to restore session", zap.String("host", recipe.Host), zap.Error(err))
+	}
+
+	s.log.Info("logging in", zap.String("host", recipe.Host))
+	err = s.submitLogin(ctx, recipe)
+	if err != nil {
+		return fmt.Errorf("failed to log into %s: %w", recipe.Host, err)
+	}
+	s.sessions.loggedIn[recipe.Host] = true
+
+	err = s.saveSession(recipe)
+	if err != nil {
+		s.log.Warn("failed to save session", zap.String("host", recipe.Host), zap.Error(err))
+	}
+
+	return nil
+}
+
+// submitLogin fills in the login form of the recipe. If a restored session is still valid, the form isn't shown.
+func (s *Scraper) submitLogin(ctx context.Context, recipe LoginRecipe) error {
+	// the login URL is configured by the operator, so the robots.txt doesn't apply to it
+	release, err := s.hosts.acquire(ctx, recipe.URL, 0)
+	if err != nil {
+		return err
+	}
+	err = s.open(ctx, recipe.URL)
+	release()
+	if err != nil {
+		return err
+	}
+
+	shown, _, err := s.getPage().Has(recipe.Email)
+	if err != nil {
+		return fmt.Errorf("failed to find login form: %w", err)
+	}
+	if !shown {
+		s.log.Info("session still valid", zap.String("host", recipe.Host))
+
+		return nil
+	}
+
+	if s.loginEmail == "" || s.loginPassword == "" {
+		return errors.New("no login credentials configured")
+	}
+	err = s.EnterInput(recipe.Email, s.loginEmail)
+	if err != nil {
+		return err
+	}
+	err = s.EnterInput(recipe.Password, s.loginPassword)
+	if err != nil {
+		return err
+	}
+	err = s.submit(recipe.Submit)
+	if err != nil {
+		return err
+	}
+
+	if recipe.OTP != "" {
+		err = s.enterOTP(recipe)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed, _, err := s.getPage().Has(recipe.Email)
+	if err != nil {
+		return fmt.Errorf("failed to check login form: %w", err)
+	}
+	if failed {
+		return ErrLoginFailed
+	}
+
+	return nil
+}
+
+func (s *Scraper) enterOTP(recipe LoginRecipe) error {
+	if s.oTPSecret == "" {
+		return errors.New("no otp secret configured")
+	}
+	code, err := totp(s.oTPSecret, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = s.EnterInput(recipe.OTP, code)
+	if err != nil {
+		return err
+	}
+	if recipe.OTPSubmit != "" {
+		return s.submit(recipe.OTPSubmit)
+	}
+
+	err = s.getPage().Keyboard.Type(input.Enter)
+	if err != nil {
+		return fmt.Errorf("failed to submit otp: %w", err)
+	}
+	err = s.getPage().WaitStable(time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+
+	return nil
+}
+
+// submit clicks the button and waits for the next page.
+func (s *Scraper) submit(selector string) error {
+	err := s.ClickButton(selector)
+	if err != nil {
+		return err
+	}
+
+	err = s.getPage().WaitStable(time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to wait for page to load: %w", err)
+	}
+
+	return nil
+}
+
+// sessionFile returns the file the session cookies of the host are saved to, empty if sessions aren't persisted.
+func (s *Scraper) sessionFile(host string) string {
+	if s.sessionDir == "" {
+		return ""
+	}
+
+	return filepath.Join(s.sessionDir, strings.ReplaceAll(strings.ToLower(host), ":", "_")+".json")
+}
+
+func (s *Scraper) restoreSession(recipe LoginRecipe) error {
+	filename := s.sessionFile(recipe.Host)
+	if filename == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var cookies []*proto.NetworkCookie
+	err = json.Unmarshal(data, &cookies)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+
+	err = s.getPage().SetCookies(proto.CookiesToParams(cookies))
+	if err != nil {
+		return fmt.Errorf("failed to set cookies: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Scraper) saveSession(recipe LoginRecipe) error {
+	filename := s.sessionFile(recipe.Host)
+	if filename == "" {
+		return nil
+	}
+
+	cookies, err := s.getPage().Cookies(sessionURLs(recipe))
+	if err != nil {
+		return fmt.Errorf("failed to get cookies: %w", err)
+	}
+	data, err := json.Marshal(cookies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	err = os.MkdirAll(s.sessionDir, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	err = os.WriteFile(filename, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return nil
+}
+
+// sessionURLs returns the URLs whose cookies make up the session: the login page and,
+// if the login page is on another host, the host of the recipe.
+func sessionURLs(recipe LoginRecipe) []string {
+	urls := []string{recipe.URL}
+	u, err := url.Parse(recipe.URL)
+	if err != nil || strings.EqualFold(u.Host, recipe.Host) {
+		return urls
+	}
+
+	return append(urls, u.Scheme+"://"+recipe.Host+"/")
+}
+
+// totp generates the time-based one-time password of RFC 6238 for the base32 secret:
+// six digits of the HMAC-SHA1 of the current 30 second step.
+func totp(secret string, t time.Time) (string, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode otp secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", code%1_000_000), nil
+}
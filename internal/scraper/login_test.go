@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	// test vectors of RFC 6238 for the ASCII secret "12345678901234567890", truncated to six digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totp(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Error generating otp: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+
+	// secrets are often shown lowercase in groups
+	got, _ := totp("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", time.Unix(59, 0))
+	if got != "287082" {
+		t.Errorf("Expected the grouped secret to be accepted, got %s", got)
+	}
+	_, err := totp("not base32!", time.Now())
+	if err == nil {
+		t.Error("Expected an invalid secret to fail")
+	}
+}
+
+func TestLoadLoginRecipes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `[{"host": "backoffice.example.com", "url": "https://auth.example.com/login", "email": "#email", "password": "#password", "submit": "button[type=submit]", "otp": "#otp"}]`,
+		},
+		{name: "invalid json", data: `[{"host": }]`, wantErr: "failed to unmarshal login recipes"},
+		{name: "missing selector", data: `[{"host": "backoffice.example.com", "url": "https://backoffice.example.com/login", "email": "#email", "password": "#password"}]`, wantErr: "lacks the host, url or a selector"},
+		{name: "missing host", data: `[{"url": "https://backoffice.example.com/login", "email": "#email", "password": "#password", "submit": "button"}]`, wantErr: "lacks the host, url or a selector"},
+		{name: "relative url", data: `[{"host": "backoffice.example.com", "url": "/login", "email": "#email", "password": "#password", "submit": "button"}]`, wantErr: "has no absolute url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "logins.json")
+			err := os.WriteFile(filename, []byte(tt.data), 0o600)
+			if err != nil {
+				t.Fatalf("Error writing recipes: %v", err)
+			}
+
+			recipes, err := LoadLoginRecipes(filename)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error loading recipes: %v", err)
+			}
+			if len(recipes) != 1 || recipes[0].OTP != "#otp" {
+				t.Errorf("Unexpected recipes %+v", recipes)
+			}
+		})
+	}
+
+	_, err := LoadLoginRecipes(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file to fail, got %v", err)
+	}
+}
+
+func TestLoginRecipe(t *testing.T) {
+	s := &settings{loginRecipes: []LoginRecipe{
+		{Host: "Backoffice.example.com", URL: "https://auth.example.com/login"},
+		{Host: "escape.example.com:8443", URL: "https://escape.example.com:8443/login"},
+	}}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://backoffice.example.com/rooms", "Backoffice.example.com"},
+		{"https://BACKOFFICE.example.com/rooms", "Backoffice.example.com"},
+		{"https://de.backoffice.example.com/rooms", "Backoffice.example.com"},
+		{"https://escape.example.com:8443/rooms", "escape.example.com:8443"},
+		// the login page on another host and hosts merely ending like the recipe host need no login
+		{"https://auth.example.com/login", ""},
+		{"https://notbackoffice.example.com/rooms", ""},
+		{"https://example.com/", ""},
+		{"https://escape.example.com/rooms", ""},
+	}
+
+	for _, tt := range tests {
+		recipe, ok := s.loginRecipe(tt.url)
+		if ok != (tt.want != "") || recipe.Host != tt.want {
+			t.Errorf("loginRecipe(%q) = %q, %t, want %q", tt.url, recipe.Host, ok, tt.want)
+		}
+	}
+}
+
+func TestSessionURLs(t *testing.T) {
+	tests := []struct {
+		recipe LoginRecipe
+		want   []string
+	}{
+		{LoginRecipe{Host: "backoffice.example.com", URL: "https://backoffice.example.com/login"}, []string{"https://backoffice.example.com/login"}},
+		{LoginRecipe{Host: "backoffice.example.com", URL: "https://auth.example.com/login"}, []string{"https://auth.example.com/login", "https://backoffice.example.com/"}},
+	}
+
+	for _, tt := range tests {
+		if got := sessionURLs(tt.recipe); !slices.Equal(got, tt.want) {
+			t.Errorf("sessionURLs(%+v) = %v, want %v", tt.recipe, got, tt.want)
+		}
+	}
+}
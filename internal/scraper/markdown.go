@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// textRenderer renders cleaned html as markdown or plain text.
+type textRenderer struct {
+	buf       strings.Builder
+	markdown  bool
+	listDepth int
+	// newlines is the number of line breaks to write in front of the next text
+	newlines int
+}
+
+// renderText renders the node as markdown, if markdown is set, or as plain text.
+// The links of the node are expected to be resolved already.
+func renderText(n *html.Node, markdown bool) string {
+	r := &textRenderer{
+		markdown: markdown,
+	}
+	r.render(n)
+
+	return strings.TrimSpace(r.buf.String())
+}
+
+func (r *textRenderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.write(n.Data)
+
+		return
+	case html.ElementNode:
+	default:
+		r.renderChildren(n)
+
+		return
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.block(2)
+		if r.markdown {
+			r.write(strings.Repeat("#", int(n.Data[1]-'0')))
+		}
+		r.renderChildren(n)
+		r.block(2)
+	case "p", "table", "blockquote", "figure", "form":
+		r.block(2)
+		r.renderChildren(n)
+		r.block(2)
+	case "ul", "ol":
+		// nested lists continue on the next line
+		r.block(2 - min(r.listDepth, 1))
+		r.listDepth++
+		number := 0
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "li" {
+				number++
+				r.block(1)
+				r.writeRaw(strings.Repeat("  ", r.listDepth-1))
+				if n.Data == "ol" {
+					r.writeRaw(fmt.Sprintf("%d. ", number))
+				} else {
+					r.writeRaw("- ")
+				}
+				r.renderChildren(c)
+
+				continue
+			}
+			r.render(c)
+		}
+		r.listDepth--
+		r.block(2 - min(r.listDepth, 1))
+	case "br", "hr":
+		r.block(1)
+	case "tr", "li", "div", "section", "article", "header", "footer", "main", "nav", "aside", "dl", "dt", "dd":
+		r.block(1)
+		r.renderChildren(n)
+		r.block(1)
+	case "td", "th":
+		if !r.atLineStart() {
+			r.writeRaw(" | ")
+		}
+		r.renderChildren(n)
+	case "a":
+		r.renderLink(n)
+	case "img":
+		r.renderImage(n)
+	default:
+		r.renderChildren(n)
+	}
+}
+
+func (r *textRenderer) renderChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+func (r *textRenderer) renderLink(n *html.Node) {
+	href := linkTarget(attribute(n, "href"))
+	inner := &textRenderer{
+		markdown: r.markdown,
+	}
+	inner.renderChildren(n)
+	text := strings.Join(strings.Fields(inner.buf.String()), " ")
+
+	switch {
+	case href == "":
+		r.write(text)
+	case r.markdown:
+		if text == "" {
+			text = href
+		}
+		r.write(fmt.Sprintf("[%s](%s)", text, href))
+	case text == "":
+		r.write(href)
+	default:
+		r.write(fmt.Sprintf("%s (%s)", text, href))
+	}
+}
+
+func (r *textRenderer) renderImage(n *html.Node) {
+	alt := strings.TrimSpace(attribute(n, "alt"))
+	if !r.markdown {
+		r.write(alt)
+
+		return
+	}
+
+	src := attribute(n, "src")
+	if src == "" {
+		// use the first candidate of the srcset
+		src, _, _ = strings.Cut(strings.TrimSpace(attribute(n, "srcset")), " ")
+	}
+	src = linkTarget(src)
+	if src == "" {
+		return
+	}
+
+	r.write(fmt.Sprintf("![%s](%s)", alt, src))
+}
+
+// linkTarget returns the target of a link or an empty string if it can't be navigated to.
+func linkTarget(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "#") || hasScheme(ref, "javascript:", "mailto:", "tel:") {
+		return ""
+	}
+
+	return ref
+}
+
+// block requests at least the given number of line breaks in front of the next text.
+func (r *textRenderer) block(newlines int) {
+	r.newlines = max(r.newlines, newlines)
+}
+
+// write writes text and separates it from the previous text by a space,
+// because normalizeWhitespace trims the whitespace between inline elements.
+func (r *textRenderer) write(text string) {
+	if text == "" {
+		return
+	}
+
+	if !r.atLineStart() && !strings.ContainsAny(text[:1], ".,;:!?)") {
+		last := r.buf.String()[r.buf.Len()-1:]
+		if !strings.ContainsAny(last, " (") {
+			r.buf.WriteString(" ")
+		}
+	}
+	r.writeRaw(text)
+}
+
+func (r *textRenderer) writeRaw(text string) {
+	if r.newlines > 0 && r.buf.Len() > 0 {
+		r.buf.WriteString(strings.Repeat("\n", r.newlines))
+	}
+	r.newlines = 0
+	r.buf.WriteString(text)
+}
+
+func (r *textRenderer) atLineStart() bool {
+	if r.newlines > 0 {
+		return true
+	}
+
+	s := r.buf.String()
+
+	return s == "" || strings.HasSuffix(s, "\n") || strings.HasSuffix(s, "- ") || strings.HasSuffix(s, ". ")
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,73 @@
+package scraper_test
+
+import (
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+)
+
+func TestRenderContentText(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		markdown string
+		text     string
+	}{
+		{
+			name:     "headings",
+			page:     `<html><body><h1>Die Gruft</h1><h3>Details</h3><p>Horror</p></body></html>`,
+			markdown: "# Die Gruft\n\n### Details\n\nHorror",
+			text:     "Die Gruft\n\nDetails\n\nHorror",
+		},
+		{
+			name:     "links",
+			page:     `<html><body><p>Jetzt <a href="/buchen">buchen</a> oder <a href="#top">nach oben</a> <a href="javascript:void(0)">Menü</a></p></body></html>`,
+			markdown: "Jetzt [buchen](https://escape.example.com/buchen) oder nach oben Menü",
+			text:     "Jetzt buchen (https://escape.example.com/buchen) oder nach oben Menü",
+		},
+		{
+			name:     "lists",
+			page:     `<html><body><ul><li>Horror<ul><li>60 Minuten</li></ul></li><li>2-6 Spieler</li></ul><ol><li>Eins</li><li>Zwei</li></ol></body></html>`,
+			markdown: "- Horror\n  - 60 Minuten\n- 2-6 Spieler\n\n1. Eins\n2. Zwei",
+			text:     "- Horror\n  - 60 Minuten\n- 2-6 Spieler\n\n1. Eins\n2. Zwei",
+		},
+		{
+			name:     "tables",
+			page:     `<html><body><table><tr><th>Raum</th><th>Preis</th></tr><tr><td>Die Gruft</td><td>99 €</td></tr></table></body></html>`,
+			markdown: "Raum | Preis\nDie Gruft | 99 €",
+			text:     "Raum | Preis\nDie Gruft | 99 €",
+		},
+		{
+			name:     "images",
+			page:     `<html><body><img src="/gruft.jpg" alt="Die Gruft"></body></html>`,
+			markdown: "![Die Gruft](https://escape.example.com/gruft.jpg)",
+			text:     "Die Gruft",
+		},
+		{
+			name:     "scripts and styles",
+			page:     `<html><head><style>p{color:red}</style></head><body><script>alert(1)</script><p>Text</p><style>.x{}</style><noscript>JS</noscript></body></html>`,
+			markdown: "Text",
+			text:     "Text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown, err := scraper.RenderContent(tt.page, "https://escape.example.com/rooms/", scraper.FormatMarkdown)
+			if err != nil {
+				t.Fatalf("Error rendering markdown: %v", err)
+			}
+			if markdown != tt.markdown {
+				t.Errorf("Unexpected markdown\n got %q\nwant %q", markdown, tt.markdown)
+			}
+
+			text, err := scraper.RenderContent(tt.page, "https://escape.example.com/rooms/", scraper.FormatText)
+			if err != nil {
+				t.Fatalf("Error rendering text: %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("Unexpected text\n got %q\nwant %q", text, tt.text)
+			}
+		})
+	}
+}
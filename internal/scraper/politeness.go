@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter limits the navigations of all pages of a browser per host
+// to a maximum concurrency and a minimum delay between their starts.
+type hostLimiter struct {
+	minDelay       time.Duration
+	maxConcurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots chan struct{}
+	mu    sync.Mutex
+	next  time.Time
+}
+
+func newHostLimiter(minDelay time.Duration, maxConcurrency int) *hostLimiter {
+	return &hostLimiter{
+		minDelay:       minDelay,
+		maxConcurrency: max(maxConcurrency, 1),
+		hosts:          make(map[string]*hostState),
+	}
+}
+
+// acquire waits until the host of the URL may be navigated and returns the function releasing it.
+// The delay is raised to the crawl delay if that is longer. Waiting stops when the context is done.
+func (l *hostLimiter) acquire(ctx context.Context, rawURL string, crawlDelay time.Duration) (func(), error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	host := l.host(strings.ToLower(u.Hostname()))
+	select {
+	case host.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() {
+		<-host.slots
+	}
+
+	host.mu.Lock()
+	wait := time.Until(host.next)
+	start := time.Now().Add(max(wait, 0))
+	host.next = start.Add(max(l.minDelay, crawlDelay))
+	host.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		release()
+
+		return nil, ctx.Err()
+	}
+
+	return release, nil
+}
+
+func (l *hostLimiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	host := l.hosts[name]
+	if host == nil {
+		host = &hostState{slots: make(chan struct{}, l.maxConcurrency)}
+		l.hosts[name] = host
+	}
+
+	return host
+}
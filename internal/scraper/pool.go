@@ -0,0 +1,212 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ErrPoolClosed is returned by Get after the pool was closed.
+var ErrPoolClosed = errors.New("page pool closed")
+
+// ErrBrowserUnreachable marks errors of a lost browser connection. Put recovers the browser after them.
+var ErrBrowserUnreachable = errors.New("browser unreachable")
+
+// Pool hands out at most size pages of a browser at once and reuses them.
+// If a page fails with ErrBrowserUnreachable and the browser is a Recoverer, a crashed browser is relaunched
+// and the pages of the crashed one are dropped.
+type Pool struct {
+	log     *zap.Logger
+	browser ScraperBrowser
+	slots   chan struct{}
+	// recovering lets one recovery at a time check the browser, without blocking the other pages
+	recovering sync.Mutex
+
+	mu         sync.Mutex
+	idle       []ScraperPage
+	generation int
+	// generations holds the browser generation of every handed out page
+	generations map[ScraperPage]int
+	closed      bool
+}
+
+// NewPool creates a pool of up to size pages of the browser. The pool owns the browser, Close closes it.
+func NewPool(log *zap.Logger, browser ScraperBrowser, size int) *Pool {
+	return &Pool{
+		log:         log,
+		browser:     browser,
+		slots:       make(chan struct{}, max(size, 1)),
+		generations: make(map[ScraperPage]int),
+	}
+}
+
+// Size returns the maximum number of pages.
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+// Get waits for a free page. It has to be returned with Put.
+func (p *Pool) Get(ctx context.Context) (ScraperPage, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	page, err := p.page()
+	if err != nil {
+		<-p.slots
+
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (p *Pool) page() (ScraperPage, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+
+		return nil, ErrPoolClosed
+	}
+	if len(p.idle) > 0 {
+		page := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.generations[page] = p.generation
+		p.mu.Unlock()
+
+		return page, nil
+	}
+	p.mu.Unlock()
+
+	page, generation, err := p.create()
+	if err != nil && p.recover() {
+		page, generation, err = p.create()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.generations[page] = generation
+
+	return page, nil
+}
+
+// create creates a page of the browser. The generation is taken before, so a page created
+// while the browser is relaunched belongs to the crashed one.
+func (p *Pool) create() (ScraperPage, int, error) {
+	p.mu.Lock()
+	generation := p.generation
+	p.mu.Unlock()
+
+	page, err := p.browser.CreatePage()
+
+	return page, generation, err
+}
+
+// Put returns the page to the pool. A browser error of its last use triggers the crash recovery.
+func (p *Pool) Put(page ScraperPage, err error) {
+	defer func() {
+		<-p.slots
+	}()
+
+	if errors.Is(err, ErrBrowserUnreachable) {
+		p.recover()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	generation := p.generations[page]
+	delete(p.generations, page)
+	if p.closed || generation != p.generation {
+		closePage(p.log, page)
+
+		return
+	}
+	p.idle = append(p.idle, page)
+}
+
+// Do runs the function with a page of the pool.
+func (p *Pool) Do(ctx context.Context, fn func(page ScraperPage) error) error {
+	page, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(page)
+	p.Put(page, err)
+
+	return err
+}
+
+// recover relaunches a crashed browser and drops the pages of the crashed one. It reports whether the browser was relaunched.
+// The browser is checked without holding the lock.
+func (p *Pool) recover() bool {
+	recoverer, ok := p.browser.(Recoverer)
+	if !ok {
+		return false
+	}
+
+	p.recovering.Lock()
+	defer p.recovering.Unlock()
+
+	relaunched, err := recoverer.Recover()
+	if err != nil {
+		p.log.Error("failed to recover browser", zap.Error(err))
+
+		return false
+	}
+	if !relaunched {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.generation++
+	p.idle = nil
+
+	return true
+}
+
+// Close closes the idle pages and the browser. Pages in use are closed when they are put back.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	for _, page := range p.idle {
+		closePage(p.log, page)
+	}
+	p.idle = nil
+
+	if closer, ok := p.browser.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func closePage(log *zap.Logger, page ScraperPage) {
+	closer, ok := page.(io.Closer)
+	if !ok {
+		return
+	}
+
+	err := closer.Close()
+	if err != nil {
+		log.Warn("failed to close page", zap.Error(err))
+	}
+}
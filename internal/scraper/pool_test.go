@@ -0,0 +1,203 @@
+package scraper_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper/scrapertest"
+	"go.uber.org/zap"
+)
+
+// crashingBrowser creates closable pages and reports a crash once it is marked crashed.
+type crashingBrowser struct {
+	*scrapertest.Browser
+	created  atomic.Int32
+	crashed  atomic.Bool
+	closed   atomic.Bool
+	relaunch atomic.Int32
+	checks   atomic.Int32
+	// checking blocks the liveness check of Recover until it is closed, if set
+	checking chan struct{}
+}
+
+type closablePage struct {
+	scraper.ScraperPage
+	closed bool
+}
+
+func (p *closablePage) Close() error {
+	p.closed = true
+
+	return nil
+}
+
+func (b *crashingBrowser) CreatePage() (scraper.ScraperPage, error) {
+	b.created.Add(1)
+	page, err := b.Browser.CreatePage()
+
+	return &closablePage{ScraperPage: page}, err
+}
+
+func (b *crashingBrowser) Recover() (bool, error) {
+	b.checks.Add(1)
+	if b.checking != nil {
+		<-b.checking
+	}
+	if !b.crashed.Swap(false) {
+		return false, nil
+	}
+	b.relaunch.Add(1)
+
+	return true, nil
+}
+
+func (b *crashingBrowser) Close() error {
+	b.closed.Store(true)
+
+	return nil
+}
+
+func TestPoolRunJobs(t *testing.T) {
+	browser := &crashingBrowser{Browser: scrapertest.NewBrowser(nil)}
+	pool := scraper.NewPool(zap.NewNop(), browser, 3)
+
+	var running, maxRunning atomic.Int32
+	var mu sync.Mutex
+	busy := map[string]bool{}
+	jobs := []scraper.Job{}
+	for i := range 12 {
+		key := fmt.Sprintf("model-%d", i%4)
+		jobs = append(jobs, scraper.Job{
+			Key: key,
+			Run: func(_ context.Context, _ scraper.ScraperPage) error {
+				mu.Lock()
+				if busy[key] {
+					t.Errorf("Jobs of %s run at once", key)
+				}
+				busy[key] = true
+				mu.Unlock()
+
+				n := running.Add(1)
+				for {
+					current := maxRunning.Load()
+					if n <= current || maxRunning.CompareAndSwap(current, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+
+				mu.Lock()
+				busy[key] = false
+				mu.Unlock()
+				if i == 5 {
+					return errors.New("job failed")
+				}
+
+				return nil
+			},
+		})
+	}
+
+	err := pool.RunJobs(context.Background(), jobs)
+	if err == nil || err.Error() != "job failed" {
+		t.Errorf("Expected the error of the failed job, got %v", err)
+	}
+	if got := maxRunning.Load(); got > 3 {
+		t.Errorf("Expected at most 3 jobs at once, got %d", got)
+	}
+	if got := browser.created.Load(); got > 3 {
+		t.Errorf("Expected at most 3 pages, got %d", got)
+	}
+
+	err = pool.Close()
+	if err != nil {
+		t.Fatalf("Error closing pool: %v", err)
+	}
+	if !browser.closed.Load() {
+		t.Error("Expected the browser to be closed")
+	}
+	_, err = pool.Get(context.Background())
+	if !errors.Is(err, scraper.ErrPoolClosed) {
+		t.Errorf("Expected a closed pool, got %v", err)
+	}
+}
+
+func TestPoolRecovers(t *testing.T) {
+	browser := &crashingBrowser{Browser: scrapertest.NewBrowser(nil)}
+	pool := scraper.NewPool(zap.NewNop(), browser, 1)
+
+	page, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting page: %v", err)
+	}
+	browser.crashed.Store(true)
+	pool.Put(page, errors.New("failed to find element"))
+	if browser.checks.Load() != 0 {
+		t.Error("Expected no recovery after a page error")
+	}
+
+	page, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting page: %v", err)
+	}
+	pool.Put(page, fmt.Errorf("failed to navigate: %w: %w", scraper.ErrBrowserUnreachable, errors.New("websocket: close 1006")))
+
+	if browser.relaunch.Load() != 1 {
+		t.Errorf("Expected the browser to be relaunched")
+	}
+	if !page.(*closablePage).closed {
+		t.Error("Expected the page of the crashed browser to be closed")
+	}
+
+	next, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting page: %v", err)
+	}
+	if next == page || browser.created.Load() != 2 {
+		t.Error("Expected a new page after the relaunch")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait for the only page, got %v", err)
+	}
+}
+
+func TestPoolRecoversWithoutBlocking(t *testing.T) {
+	browser := &crashingBrowser{Browser: scrapertest.NewBrowser(nil), checking: make(chan struct{})}
+	pool := scraper.NewPool(zap.NewNop(), browser, 2)
+
+	page, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting page: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Put(page, scraper.ErrBrowserUnreachable)
+	}()
+
+	// the other pages are handed out while the browser is checked
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	other, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Error getting page during the recovery: %v", err)
+	}
+	close(browser.checking)
+	<-done
+	pool.Put(other, nil)
+
+	if browser.checks.Load() != 1 || browser.relaunch.Load() != 0 {
+		t.Errorf("Expected one check without relaunch, got %d checks and %d relaunches", browser.checks.Load(), browser.relaunch.Load())
+	}
+}
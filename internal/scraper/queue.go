@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// Job is work which needs a page.
+type Job struct {
+	// Key serializes jobs, jobs with the same key never run at once, e.g. the jobs of one llm.
+	Key string
+	Run func(ctx context.Context, page ScraperPage) error
+}
+
+// RunJobs runs the jobs on the pages of the pool, at most Size at once. Jobs are started in order,
+// except that a job whose key is busy is passed over. It returns the joined errors of the jobs.
+func (p *Pool) RunJobs(ctx context.Context, jobs []Job) error {
+	q := &queue{
+		pending: slices.Clone(jobs),
+		busy:    make(map[string]bool),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	var wg sync.WaitGroup
+	for range min(p.Size(), len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				job, ok := q.next()
+				if !ok {
+					return
+				}
+
+				err := p.Do(ctx, func(page ScraperPage) error {
+					return job.Run(ctx, page)
+				})
+				q.done(job, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return q.errs
+}
+
+type queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Job
+	busy    map[string]bool
+	errs    error
+}
+
+// next waits for the first pending job whose key is not busy. It returns false if no jobs are left.
+func (q *queue) next() (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) > 0 {
+		for i, job := range q.pending {
+			if q.busy[job.Key] {
+				continue
+			}
+
+			q.pending = slices.Delete(q.pending, i, i+1)
+			q.busy[job.Key] = true
+
+			return job, true
+		}
+		q.cond.Wait()
+	}
+
+	return Job{}, false
+}
+
+func (q *queue) done(job Job, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.busy[job.Key] = false
+	q.errs = errors.Join(q.errs, err)
+	q.cond.Broadcast()
+}
@@ -0,0 +1,109 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrReplayInteraction is returned by replayed pages for interactions which need a live browser.
+var ErrReplayInteraction = errors.New("interactions are not supported by replayed pages")
+
+// ReplayBrowser serves pages from an archive instead of a live browser,
+// so runs of different models get identical inputs.
+type ReplayBrowser struct {
+	archive *Archive
+	format  Format
+}
+
+// NewReplayBrowser creates a browser replaying the snapshots of the archive in the format.
+func NewReplayBrowser(archive *Archive, format Format) *ReplayBrowser {
+	return &ReplayBrowser{
+		archive: archive,
+		format:  format,
+	}
+}
+
+func (b *ReplayBrowser) CreatePage() (ScraperPage, error) {
+	return &replayPage{browser: b}, nil
+}
+
+type replayPage struct {
+	browser  *ReplayBrowser
+	snapshot *Snapshot
+}
+
+func (p *replayPage) ClickButton(selector string) error {
+	return fmt.Errorf("failed to click %s: %w", selector, ErrReplayInteraction)
+}
+
+func (p *replayPage) EnterInput(selector, _ string) error {
+	return fmt.Errorf("failed to input into %s: %w", selector, ErrReplayInteraction)
+}
+
+func (p *replayPage) Navigate(_ context.Context, url string) error {
+	snapshot, err := p.browser.archive.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to navigate to url: %s: %w", url, err)
+	}
+	p.snapshot = &snapshot
+
+	return nil
+}
+
+// PageContent returns the archived content if it has the format of the browser,
+// otherwise the archived html is rendered in the format.
+func (p *replayPage) PageContent() (string, int, int, error) {
+	if p.snapshot == nil {
+		return "", 0, 0, errors.New("no page navigated")
+	}
+
+	content := p.snapshot.Content
+	if p.snapshot.Format != p.browser.format {
+		var err error
+		content, err = RenderContent(p.snapshot.HTML, p.snapshot.FinalURL, p.browser.format)
+		if err != nil {
+			return "", 0, 0, err
+		}
+	}
+
+	return content, len(p.snapshot.HTML), len(content), nil
+}
+
+// GetScreenshotTiles returns the archived screenshot tiles. Snapshots archived without
+// tiles return their full-page screenshot as a single tile.
+func (p *replayPage) GetScreenshotTiles() ([][]byte, error) {
+	if p.snapshot != nil && len(p.snapshot.Tiles) > 0 {
+		return p.snapshot.Tiles, nil
+	}
+
+	screenshot, err := p.GetScreenshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{screenshot}, nil
+}
+
+// Metadata describes the replayed snapshot, with the consent banner handled when it was archived.
+func (p *replayPage) Metadata() Metadata {
+	if p.snapshot == nil {
+		return Metadata{}
+	}
+
+	return Metadata{
+		URL:     p.snapshot.URL,
+		Consent: p.snapshot.Consent,
+	}
+}
+
+func (p *replayPage) GetScreenshot() ([]byte, error) {
+	if p.snapshot == nil {
+		return nil, errors.New("no page navigated")
+	}
+	if len(p.snapshot.Screenshot) == 0 {
+		return nil, fmt.Errorf("no screenshot archived for %s", p.snapshot.URL)
+	}
+
+	return p.snapshot.Screenshot, nil
+}
@@ -0,0 +1,102 @@
+package scraper_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+)
+
+func TestReplayBrowser(t *testing.T) {
+	archive, err := scraper.OpenArchive("testdata/archive")
+	if err != nil {
+		t.Fatalf("Error opening archive: %v", err)
+	}
+
+	tests := []struct {
+		url    string
+		format scraper.Format
+		want   string
+	}{
+		{"https://escape.example.com", scraper.FormatHTML, `<a href="https://escape.example.com/rooms/gruft/">Die Gruft</a>`},
+		{"https://escape.example.com/rooms/gruft/", scraper.FormatMarkdown, "![Die Gruft](https://escape.example.com/img/gruft.webp)"},
+		{"https://escape.example.com/rooms/gruft/", scraper.FormatText, "Jetzt buchen (https://booking.example.com/gruft)"},
+	}
+
+	for _, tt := range tests {
+		page, err := scraper.NewReplayBrowser(archive, tt.format).CreatePage()
+		if err != nil {
+			t.Fatalf("Error creating page: %v", err)
+		}
+
+		err = page.Navigate(context.Background(), tt.url)
+		if err != nil {
+			t.Fatalf("Error navigating to %s: %v", tt.url, err)
+		}
+
+		content, maxLength, reducedLength, err := page.PageContent()
+		if err != nil {
+			t.Fatalf("Error getting page content: %v", err)
+		}
+		if !strings.Contains(content, tt.want) {
+			t.Errorf("Expected %s content of %s to contain %q, got %q", tt.format, tt.url, tt.want, content)
+		}
+		if reducedLength != len(content) || maxLength <= reducedLength {
+			t.Errorf("Unexpected lengths %d and %d", maxLength, reducedLength)
+		}
+	}
+
+	page, _ := scraper.NewReplayBrowser(archive, scraper.FormatHTML).CreatePage()
+	err = page.Navigate(context.Background(), "https://escape.example.com/rooms/unknown/")
+	if !errors.Is(err, scraper.ErrNotArchived) {
+		t.Errorf("Expected ErrNotArchived, got %v", err)
+	}
+	err = page.ClickButton("#cookie-accept")
+	if !errors.Is(err, scraper.ErrReplayInteraction) {
+		t.Errorf("Expected ErrReplayInteraction, got %v", err)
+	}
+}
+
+func TestArchiveSave(t *testing.T) {
+	archive, err := scraper.OpenArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening archive: %v", err)
+	}
+
+	err = archive.Save(scraper.Snapshot{
+		URL:        "https://escape.example.com/buchen",
+		FinalURL:   "https://escape.example.com/booking/",
+		Format:     scraper.FormatText,
+		HTML:       "<html><body>Buchen</body></html>",
+		Content:    "Buchen",
+		Screenshot: []byte("webp"),
+		Tiles:      [][]byte{[]byte("top"), []byte("bottom")},
+	})
+	if err != nil {
+		t.Fatalf("Error saving snapshot: %v", err)
+	}
+
+	snapshot, err := archive.Get("https://escape.example.com/booking")
+	if err != nil {
+		t.Fatalf("Error getting snapshot by final URL: %v", err)
+	}
+	if snapshot.Content != "Buchen" || string(snapshot.Screenshot) != "webp" || snapshot.Timestamp.IsZero() {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+
+	// the replay returns the archived tiles like the live browser took them
+	page, _ := scraper.NewReplayBrowser(archive, scraper.FormatText).CreatePage()
+	err = page.Navigate(context.Background(), "https://escape.example.com/buchen")
+	if err != nil {
+		t.Fatalf("Error navigating: %v", err)
+	}
+	tiles, err := page.GetScreenshotTiles()
+	if err != nil {
+		t.Fatalf("Error getting screenshot tiles: %v", err)
+	}
+	if len(tiles) != 2 || string(tiles[0]) != "top" || string(tiles[1]) != "bottom" {
+		t.Errorf("Expected the archived tiles, got %q", tiles)
+	}
+}
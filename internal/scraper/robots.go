@@ -0,0 +1,249 @@
+package scraper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsMaxSize is the part of a robots.txt which is parsed, like RFC 9309 allows.
+	robotsMaxSize = 500 * 1024
+	// maxCrawlDelay caps the crawl delay of a robots.txt, so a single website can't stall a crawl.
+	maxCrawlDelay = 30 * time.Second
+)
+
+// ErrDisallowed is returned by Navigate for URLs the robots.txt of the website disallows.
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// robotsRule allows or disallows the paths matching its pattern.
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsGroup holds the rules of the user agent of the scraper.
+type robotsGroup struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots parses a robots.txt and returns the group applying to the product token of the user agent.
+// Like RFC 9309 demands, the product tokens are compared case-insensitively, the group "*" is used if none matches.
+func parseRobots(r io.Reader, agent string) (robotsGroup, error) {
+	agent = productToken(agent)
+	groups := map[string]*robotsGroup{}
+	current := []string{}
+	inRules := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, robotsMaxSize))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// consecutive user-agent lines share the following rules
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			name := productToken(value)
+			current = append(current, name)
+			if groups[name] == nil {
+				groups[name] = &robotsGroup{}
+			}
+
+			continue
+		}
+
+		inRules = true
+		for _, name := range current {
+			group := groups[name]
+			switch key {
+			case "allow", "disallow":
+				// an empty disallow allows everything
+				if value != "" {
+					group.rules = append(group.rules, robotsRule{pattern: value, allow: key == "allow"})
+				}
+			case "crawl-delay":
+				seconds, err := strconv.ParseFloat(value, 64)
+				if err == nil && seconds > 0 {
+					group.crawlDelay = min(time.Duration(seconds*float64(time.Second)), maxCrawlDelay)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return robotsGroup{}, fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+
+	group := groups[agent]
+	if group == nil {
+		group = groups["*"]
+	}
+	if group == nil {
+		return robotsGroup{}, nil
+	}
+
+	return *group, nil
+}
+
+// productToken returns the lower case product token of a user agent, e.g. "esc-llm-webscraper" of "esc-llm-webscraper/1.0 (+https://...)".
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	token, _, _ = strings.Cut(token, " ")
+
+	return strings.ToLower(token)
+}
+
+// allowed reports whether the rules allow the path. The longest matching rule wins, allow rules win ties.
+func (g robotsGroup) allowed(path string) bool {
+	allow := true
+	length := -1
+	for _, rule := range g.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > length || (len(rule.pattern) == length && rule.allow) {
+			allow = rule.allow
+			length = len(rule.pattern)
+		}
+	}
+
+	return allow
+}
+
+// matchRobotsPattern matches the path against a pattern with * wildcards and an optional $ end anchor.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		// the last part of an anchored pattern has to match the end
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
+
+// robots fetches the robots.txt of every host once and checks URLs against it.
+type robots struct {
+	client    *http.Client
+	userAgent string
+	// agent is the product token the groups are matched with
+	agent string
+
+	mu     sync.Mutex
+	groups map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	once  sync.Once
+	group robotsGroup
+}
+
+func newRobots(client *http.Client, userAgent string) *robots {
+	return &robots{
+		client:    client,
+		userAgent: userAgent,
+		agent:     productToken(userAgent),
+		groups:    make(map[string]*robotsEntry),
+	}
+}
+
+// check returns ErrDisallowed if the robots.txt of the host disallows the URL,
+// and the crawl delay the robots.txt asks for.
+func (r *robots) check(rawURL string) (time.Duration, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return 0, nil
+	}
+
+	group := r.group(u.Scheme + "://" + u.Host)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !group.allowed(path) {
+		return group.crawlDelay, fmt.Errorf("%w: %s", ErrDisallowed, rawURL)
+	}
+
+	return group.crawlDelay, nil
+}
+
+func (r *robots) group(origin string) robotsGroup {
+	r.mu.Lock()
+	entry := r.groups[origin]
+	if entry == nil {
+		entry = &robotsEntry{}
+		r.groups[origin] = entry
+	}
+	r.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.group = r.fetch(origin)
+	})
+
+	return entry.group
+}
+
+// fetch loads the robots.txt of the origin. Like RFC 9309 demands, a missing robots.txt allows
+// everything and a server error disallows everything. An unreachable robots.txt allows everything,
+// the navigation will fail anyway.
+func (r *robots) fetch(origin string) robotsGroup {
+	req, err := http.NewRequest(http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return robotsGroup{}
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return robotsGroup{}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return robotsGroup{rules: []robotsRule{{pattern: "/"}}}
+	case resp.StatusCode >= 400:
+		return robotsGroup{}
+	}
+
+	group, err := parseRobots(resp.Body, r.agent)
+	if err != nil {
+		return robotsGroup{}
+	}
+
+	return group
+}
@@ -0,0 +1,204 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `# example
+User-agent: *
+Disallow: /intern/
+Allow: /intern/preise
+Disallow: /*.pdf$
+
+User-agent: googlebot
+User-agent: esc-llm-webscraper
+Disallow: /buchen
+Crawl-delay: 2.5
+`
+
+func TestParseRobots(t *testing.T) {
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{agent: "otherbot", path: "/", want: true},
+		{agent: "otherbot", path: "/intern/team", want: false},
+		{agent: "otherbot", path: "/intern/preise", want: true},
+		{agent: "otherbot", path: "/flyer.pdf", want: false},
+		{agent: "otherbot", path: "/flyer.pdf?download=1", want: true},
+		{agent: "esc-llm-webscraper", path: "/intern/team", want: true},
+		{agent: "esc-llm-webscraper", path: "/buchen?room=gruft", want: false},
+	}
+
+	for _, tt := range tests {
+		group, err := parseRobots(strings.NewReader(robotsTxt), tt.agent)
+		if err != nil {
+			t.Fatalf("Error parsing robots.txt: %v", err)
+		}
+		if got := group.allowed(tt.path); got != tt.want {
+			t.Errorf("%s %s: expected allowed %t, got %t", tt.agent, tt.path, tt.want, got)
+		}
+	}
+
+	group, _ := parseRobots(strings.NewReader(robotsTxt), "esc-llm-webscraper")
+	if group.crawlDelay != 2500*time.Millisecond {
+		t.Errorf("Expected a crawl delay of 2.5s, got %s", group.crawlDelay)
+	}
+}
+
+func TestParseRobotsProductToken(t *testing.T) {
+	const robots = `User-agent: *
+Disallow: /all
+
+User-agent: esc
+User-agent: webscraper
+Disallow: /partial
+
+User-agent: ESC-LLM-Webscraper/2.0
+Disallow: /own
+Crawl-delay: 3600
+`
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		// tokens contained in the product token of the scraper don't match it
+		{agent: DefaultUserAgent, path: "/partial", want: true},
+		{agent: DefaultUserAgent, path: "/own", want: false},
+		{agent: "esc-llm-webscraper", path: "/all", want: true},
+		{agent: "esc/1.0", path: "/partial", want: false},
+		{agent: "otherbot", path: "/all", want: false},
+	}
+
+	for _, tt := range tests {
+		group, err := parseRobots(strings.NewReader(robots), tt.agent)
+		if err != nil {
+			t.Fatalf("Error parsing robots.txt: %v", err)
+		}
+		if got := group.allowed(tt.path); got != tt.want {
+			t.Errorf("%s %s: expected allowed %t, got %t", tt.agent, tt.path, tt.want, got)
+		}
+	}
+
+	group, _ := parseRobots(strings.NewReader(robots), DefaultUserAgent)
+	if group.crawlDelay != maxCrawlDelay {
+		t.Errorf("Expected the crawl delay to be capped at %s, got %s", maxCrawlDelay, group.crawlDelay)
+	}
+}
+
+func TestRobotsCheck(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+
+			return
+		}
+		fetches.Add(1)
+		if ua := r.Header.Get("User-Agent"); ua != DefaultUserAgent {
+			t.Errorf("Unexpected user agent %q", ua)
+		}
+		fmt.Fprint(w, robotsTxt)
+	}))
+	defer server.Close()
+
+	r := newRobots(server.Client(), DefaultUserAgent)
+	_, err := r.check(server.URL + "/rooms/gruft")
+	if err != nil {
+		t.Errorf("Expected the room to be allowed, got %v", err)
+	}
+	delay, err := r.check(server.URL + "/buchen")
+	if !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Expected the booking page to be disallowed, got %v", err)
+	}
+	if delay != 2500*time.Millisecond {
+		t.Errorf("Expected the crawl delay, got %s", delay)
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("Expected the robots.txt to be fetched once, got %d", fetches.Load())
+	}
+}
+
+func TestRobotsServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := newRobots(server.Client(), DefaultUserAgent).check(server.URL + "/")
+	if !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Expected everything to be disallowed, got %v", err)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(50*time.Millisecond, 1)
+
+	start := time.Now()
+	for range 3 {
+		release, err := limiter.acquire(context.Background(), "https://escape.example.com/", 0)
+		if err != nil {
+			t.Fatalf("Error acquiring host: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the navigations to be delayed, took %s", elapsed)
+	}
+
+	// other hosts are not delayed
+	start = time.Now()
+	release, _ := limiter.acquire(context.Background(), "https://other.example.com/", 0)
+	release()
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected no delay for another host, took %s", elapsed)
+	}
+
+	release, _ = limiter.acquire(context.Background(), "https://slots.example.com/", 0)
+	acquired := make(chan struct{})
+	go func() {
+		release, _ := limiter.acquire(context.Background(), "https://slots.example.com/", 0)
+		close(acquired)
+		release()
+	}()
+	select {
+	case <-acquired:
+		t.Error("Expected the second page to wait for the free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	<-acquired
+}
+
+func TestHostLimiterCancel(t *testing.T) {
+	limiter := newHostLimiter(time.Hour, 1)
+	release, err := limiter.acquire(context.Background(), "https://escape.example.com/", 0)
+	if err != nil {
+		t.Fatalf("Error acquiring host: %v", err)
+	}
+	release()
+
+	// the delay of the next navigation is cut short by the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.acquire(ctx, "https://escape.example.com/", 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to be cancelled, got %v", err)
+	}
+
+	// the slot of the cancelled navigation is free again
+	if slots := len(limiter.host("escape.example.com").slots); slots != 0 {
+		t.Errorf("Expected the slot to be released, got %d taken", slots)
+	}
+}
@@ -1,24 +1,33 @@
 package scraper
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
-	"github.com/go-rod/rod/lib/launcher/flags"
+	"github.com/go-rod/rod/lib/proto"
 	"github.com/go-rod/stealth"
 	"go.uber.org/zap"
 )
 
 type Scraper struct {
+	*settings
 	log                   *zap.Logger
 	defaultBrowserTimeout time.Duration
-	browser               *rod.Browser
-	page                  *rod.Page
-	loginEmail            string
-	loginPassword         string
-	oTPSecret             string
+	// chrome is shared by all pages of the browser
+	chrome *chrome
+	// sessions are the logins of chrome
+	sessions      *sessions
+	page          *rod.Page
+	loginEmail    string
+	loginPassword string
+	oTPSecret     string
+	// url is the last navigated URL
+	url string
+	// consent is the name of the consent rule whose banner was handled on the last navigation
+	consent          string
+	consentDismissed bool
 }
 
 func (s *Scraper) getPage() *rod.Page {
@@ -29,23 +38,53 @@ type ScraperBrowser interface {
 	CreatePage() (ScraperPage, error)
 }
 
+// Recoverer is implemented by browsers which can be relaunched after a crash.
+type Recoverer interface {
+	// Recover relaunches the browser if it is unreachable and reports whether it did.
+	Recover() (bool, error)
+}
+
 type ScraperPage interface {
 	ClickButton(selector string) error
 	EnterInput(selector, input string) error
 	PageContent() (string, int, int, error)
-	Navigate(url string) error
+	Navigate(ctx context.Context, url string) error
 	GetScreenshot() ([]byte, error)
+	// GetScreenshotTiles takes a full-page screenshot split into tiles from top to bottom, so tall pages stay legible.
+	GetScreenshotTiles() ([][]byte, error)
+	// Metadata describes the page of the last navigation.
+	Metadata() Metadata
+}
+
+// Metadata describes a navigated page.
+type Metadata struct {
+	URL string
+	// Consent is the name of the consent rule whose banner was handled, empty if there was none.
+	Consent string
+	// ConsentDismissed is set if the banner was closed with its button, not only stripped from the page.
+	ConsentDismissed bool
+}
+
+func New(log *zap.Logger, format Format, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret string, opts ...Option) (ScraperBrowser, error) {
+	settings, err := newSettings(format, proxyServer, proxyUsername, proxyPassword, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScraper(log, settings, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret)
 }
 
-func New(log *zap.Logger, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret string) (ScraperBrowser, error) {
-	page, err := newBrowser(log, proxyServer, proxyUsername, proxyPassword)
+func newScraper(log *zap.Logger, settings *settings, proxyServer, proxyUsername, proxyPassword, loginEmail, loginPassword, oTPSecret string) (*Scraper, error) {
+	chrome, err := launchChrome(log, proxyServer, proxyUsername, proxyPassword)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Scraper{
+		settings:              settings,
 		log:                   log,
-		browser:               page,
+		chrome:                chrome,
+		sessions:              newSessions(),
 		loginEmail:            loginEmail,
 		loginPassword:         loginPassword,
 		oTPSecret:             oTPSecret,
@@ -53,15 +92,26 @@ func New(log *zap.Logger, proxyServer, proxyUsername, proxyPassword, loginEmail,
 	}, nil
 }
 
+// Metadata describes the page of the last navigation.
+func (s *Scraper) Metadata() Metadata {
+	return Metadata{
+		URL:              s.url,
+		Consent:          s.consent,
+		ConsentDismissed: s.consentDismissed,
+	}
+}
+
 func (s *Scraper) CreatePage() (ScraperPage, error) {
-	s.log.Info("starting stealth page")
-	page, err := stealth.Page(s.browser)
+	page, err := s.newPage()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stealth-page: %w", err)
+		return nil, err
 	}
 
 	s.log.Info("setting fullscreen")
-	page = page.MustWindowFullscreen()
+	err = page.SetWindow(&proto.BrowserBounds{WindowState: proto.BrowserWindowStateFullscreen})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set fullscreen: %w", err)
+	}
 
 	scraper := *s
 	scraper.page = page
@@ -69,41 +119,57 @@ func (s *Scraper) CreatePage() (ScraperPage, error) {
 	return &scraper, nil
 }
 
-func newBrowser(log *zap.Logger, proxyServer string, proxyUsername string, proxyPassword string) (*rod.Browser, error) {
-	log.Info("creating new browser")
-	path, _ := launcher.LookPath()
-	launcher := launcher.
-		New().
-		Headless(true).
-		Bin(path).
-		NoSandbox(true)
-
-	if proxyServer != "" && proxyUsername != "" && proxyPassword != "" {
-		launcher.Set(flags.ProxyServer, proxyServer)
+// Close closes the page or, called on the browser, closes Chrome.
+func (s *Scraper) Close() error {
+	if s.page != nil {
+		err := s.page.Close()
+		if err != nil {
+			return fmt.Errorf("failed to close page: %w", err)
+		}
+
+		return nil
 	}
 
-	log.With(zap.Any("launcher-flags", launcher.Flags)).Info("launching browser")
-	u, err := launcher.Launch()
-	if err != nil {
-		return nil, fmt.Errorf("failed to launch new browser: %w", err)
+	return s.chrome.close()
+}
+
+// Recover relaunches Chrome if its connection died. Pages created before are unusable afterwards.
+func (s *Scraper) Recover() (bool, error) {
+	if s.chrome.alive() {
+		return false, nil
 	}
 
-	log.Info("connecting to browser")
-	browser := rod.New().ControlURL(u)
-	err = browser.Connect()
+	s.log.Warn("browser connection lost, relaunching")
+	err := s.chrome.relaunch()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+		return false, err
+	}
+	s.sessions.reset()
+
+	return true, nil
+}
+
+func (s *Scraper) newPage() (*rod.Page, error) {
+	browser := s.chrome.current()
+	if s.stealth {
+		s.log.Info("starting stealth page")
+		page, err := stealth.Page(browser)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stealth-page: %w", err)
+		}
+
+		return page, nil
 	}
 
-	if proxyServer != "" && proxyUsername != "" && proxyPassword != "" {
-		log.Info("setting up auth handler")
-		go func() {
-			err = browser.HandleAuth(proxyUsername, proxyPassword)()
-			if err != nil {
-				log.Error("failed to handle auth for proxy", zap.Error(err))
-			}
-		}()
+	s.log.Info("starting page", zap.String("user agent", s.userAgent))
+	page, err := browser.Page(proto.TargetCreateTarget{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	err = page.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: s.userAgent})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set user agent: %w", err)
 	}
 
-	return browser, nil
+	return page, nil
 }
@@ -1,7 +1,7 @@
 package scraper_test
 
 import (
-	"os"
+	"context"
 	"testing"
 
 	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
@@ -14,14 +14,19 @@ func TestWebsite(t *testing.T) {
 		t.Fatalf("Error creating logger: %v", err)
 	}
 
-	s, err := scraper.New(log, "", "", "", "", "", "")
+	archive, err := scraper.OpenArchive(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error opening archive: %v", err)
+	}
+
+	s, err := scraper.New(log, scraper.FormatHTML, "", "", "", "", "", "", scraper.WithArchive(archive))
 	if err != nil {
 		t.Fatalf("Error creating scraper: %v", err)
 	}
 
 	p, err := s.CreatePage()
 
-	err = p.Navigate("https://alfsee-escape.de/indoor-escape-room/")
+	err = p.Navigate(context.Background(), "https://alfsee-escape.de/indoor-escape-room/")
 	if err != nil {
 		t.Fatalf("Error navigating to URL: %v", err)
 	}
@@ -31,16 +36,11 @@ func TestWebsite(t *testing.T) {
 		t.Fatalf("Error getting page content: %v", err)
 	}
 
-	file, err := os.Create("output.html")
+	snapshot, err := archive.Get("https://alfsee-escape.de/indoor-escape-room/")
 	if err != nil {
-		t.Fatalf("Error creating file: %v", err)
+		t.Fatalf("Error getting archived page: %v", err)
 	}
-	defer file.Close()
-
-	_, err = file.WriteString(content)
-	if err != nil {
-		t.Fatalf("Error writing to file: %v", err)
+	if snapshot.Content != content {
+		t.Error("Expected the archived content to match the page content")
 	}
-
-	t.Log("Website content successfully written to output.html")
 }
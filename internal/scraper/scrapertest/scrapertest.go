@@ -0,0 +1,159 @@
+// Package scrapertest provides an in-memory scraper.ScraperBrowser for tests.
+package scrapertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/scraper"
+)
+
+// ErrNotFound is returned when navigating to a URL missing in the site map.
+var ErrNotFound = errors.New("page not found")
+
+// Browser serves a site map of canned HTML pages, keyed by URL.
+type Browser struct {
+	Pages  map[string]string
+	Format scraper.Format
+	// Disallowed are the URLs navigations fail for with scraper.ErrDisallowed, like a robots.txt disallowing them.
+	Disallowed []string
+	// Consent maps URLs to the name of the consent rule whose banner their pages report handled.
+	Consent map[string]string
+
+	mu          sync.Mutex
+	navigations []string
+	clicks      []string
+}
+
+// NewBrowser creates a browser serving the pages as cleaned HTML.
+func NewBrowser(pages map[string]string) *Browser {
+	return &Browser{
+		Pages:  pages,
+		Format: scraper.FormatHTML,
+	}
+}
+
+func (b *Browser) CreatePage() (scraper.ScraperPage, error) {
+	return &Page{browser: b}, nil
+}
+
+// Navigations returns the URLs navigated to by all pages of the browser.
+func (b *Browser) Navigations() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.navigations...)
+}
+
+// Clicks returns the clicked selectors of all pages of the browser.
+func (b *Browser) Clicks() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]string(nil), b.clicks...)
+}
+
+// Page is a page of the Browser.
+type Page struct {
+	browser *Browser
+	url     string
+	inputs  map[string]string
+}
+
+func (p *Page) ClickButton(selector string) error {
+	p.browser.mu.Lock()
+	defer p.browser.mu.Unlock()
+
+	p.browser.clicks = append(p.browser.clicks, selector)
+
+	return nil
+}
+
+func (p *Page) EnterInput(selector, input string) error {
+	if p.inputs == nil {
+		p.inputs = make(map[string]string)
+	}
+	p.inputs[selector] = input
+
+	return nil
+}
+
+// Input returns the text entered into the selector.
+func (p *Page) Input(selector string) string {
+	return p.inputs[selector]
+}
+
+func (p *Page) Navigate(_ context.Context, url string) error {
+	p.browser.mu.Lock()
+	p.browser.navigations = append(p.browser.navigations, url)
+	p.browser.mu.Unlock()
+
+	if slices.Contains(p.browser.Disallowed, url) {
+		return fmt.Errorf("%w: %s", scraper.ErrDisallowed, url)
+	}
+	if _, ok := p.page(url); !ok {
+		return fmt.Errorf("failed to navigate to url: %s: %w", url, ErrNotFound)
+	}
+	p.url = url
+
+	return nil
+}
+
+func (p *Page) PageContent() (string, int, int, error) {
+	html, ok := p.page(p.url)
+	if !ok {
+		return "", 0, 0, errors.New("no page navigated")
+	}
+
+	content, err := scraper.RenderContent(html, p.url, p.browser.Format)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	return content, len(html), len(content), nil
+}
+
+func (p *Page) Metadata() scraper.Metadata {
+	return scraper.Metadata{
+		URL:              p.url,
+		Consent:          p.browser.Consent[p.url],
+		ConsentDismissed: p.browser.Consent[p.url] != "",
+	}
+}
+
+func (p *Page) GetScreenshot() ([]byte, error) {
+	if p.url == "" {
+		return nil, errors.New("no page navigated")
+	}
+
+	return []byte("screenshot of " + p.url), nil
+}
+
+func (p *Page) GetScreenshotTiles() ([][]byte, error) {
+	screenshot, err := p.GetScreenshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{screenshot}, nil
+}
+
+// page looks up the html of the URL, ignoring a trailing slash.
+func (p *Page) page(url string) (string, bool) {
+	if url == "" {
+		return "", false
+	}
+	if html, ok := p.browser.Pages[url]; ok {
+		return html, true
+	}
+	if html, ok := p.browser.Pages[strings.TrimSuffix(url, "/")]; ok {
+		return html, true
+	}
+	html, ok := p.browser.Pages[url+"/"]
+
+	return html, ok
+}
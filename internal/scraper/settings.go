@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// settings are shared by all pages of a browser, whatever its backend.
+type settings struct {
+	format       Format
+	archive      *Archive
+	userAgent    string
+	stealth      bool
+	ignoreRobots bool
+	minDelay     time.Duration
+	maxPerHost   int
+	consentRules []ConsentRule
+	loginRecipes []LoginRecipe
+	sessionDir   string
+
+	robots *robots
+	hosts  *hostLimiter
+}
+
+// DefaultUserAgent identifies the scraper if stealth is disabled. Its product token is matched against robots.txt groups.
+const DefaultUserAgent = "esc-llm-webscraper/1.0 (+https://github.com/martinbockt/esc-llm-webscraper)"
+
+const (
+	// DefaultMinDelay is the default minimum delay between navigations to the same host.
+	DefaultMinDelay = time.Second
+	// DefaultMaxPerHost is the default maximum number of pages navigating the same host at once.
+	DefaultMaxPerHost = 2
+)
+
+// Option configures the scraper.
+type Option func(*settings)
+
+// WithArchive saves a snapshot of every page whose content is read to the archive.
+func WithArchive(archive *Archive) Option {
+	return func(s *settings) {
+		s.archive = archive
+	}
+}
+
+// WithUserAgent sets the user agent pages identify with if stealth is disabled,
+// and whose product token selects the robots.txt rules.
+func WithUserAgent(userAgent string) Option {
+	return func(s *settings) {
+		s.userAgent = userAgent
+	}
+}
+
+// WithStealth sets whether pages hide that they are automated, enabled by default.
+func WithStealth(enabled bool) Option {
+	return func(s *settings) {
+		s.stealth = enabled
+	}
+}
+
+// WithoutRobots disables fetching and honoring robots.txt files.
+func WithoutRobots() Option {
+	return func(s *settings) {
+		s.ignoreRobots = true
+	}
+}
+
+// WithPoliteness sets the minimum delay between navigations to the same host and the maximum
+// number of pages navigating the same host at once, across all pages of the browser.
+// The delay is raised to the crawl delay of the robots.txt.
+func WithPoliteness(minDelay time.Duration, maxPerHost int) Option {
+	return func(s *settings) {
+		s.minDelay = minDelay
+		s.maxPerHost = maxPerHost
+	}
+}
+
+// WithConsentRules sets the rules consent banners are detected and dismissed with after every navigation,
+// DefaultConsentRules by default. No rules disable the handling.
+func WithConsentRules(rules []ConsentRule) Option {
+	return func(s *settings) {
+		s.consentRules = rules
+	}
+}
+
+// WithLoginRecipes logs into the websites of the recipes with the login credentials of the browser
+// before navigating to their pages. Only Chrome pages can log in.
+func WithLoginRecipes(recipes []LoginRecipe) Option {
+	return func(s *settings) {
+		s.loginRecipes = recipes
+	}
+}
+
+// WithSessionDir saves the session cookies of logins to the directory and restores them, so
+// the login form isn't filled in again as long as a session is valid.
+func WithSessionDir(dir string) Option {
+	return func(s *settings) {
+		s.sessionDir = dir
+	}
+}
+
+func newSettings(format Format, proxyServer, proxyUsername, proxyPassword string, opts []Option) (*settings, error) {
+	s := &settings{
+		format:       format,
+		userAgent:    DefaultUserAgent,
+		stealth:      true,
+		minDelay:     DefaultMinDelay,
+		maxPerHost:   DefaultMaxPerHost,
+		consentRules: DefaultConsentRules(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.hosts = newHostLimiter(s.minDelay, s.maxPerHost)
+	if !s.ignoreRobots {
+		client, err := proxyClient(proxyServer, proxyUsername, proxyPassword)
+		if err != nil {
+			return nil, err
+		}
+		s.robots = newRobots(client, s.userAgent)
+	}
+
+	return s, nil
+}
+
+// admit waits until the URL may be navigated and returns the function releasing its host.
+// It fails with ErrDisallowed if the robots.txt disallows the URL.
+func (s *settings) admit(ctx context.Context, url string) (func(), error) {
+	var crawlDelay time.Duration
+	if s.robots != nil {
+		var err error
+		crawlDelay, err = s.robots.check(url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.hosts.acquire(ctx, url, crawlDelay)
+}
+
+// saveSnapshot archives a page in the format of the settings if an archive is set.
+// Failures are logged only, they don't affect the scrape.
+func (s *settings) saveSnapshot(log *zap.Logger, snapshot Snapshot) {
+	if s.archive == nil {
+		return
+	}
+	if snapshot.URL == "" {
+		snapshot.URL = snapshot.FinalURL
+	}
+	snapshot.Format = s.format
+
+	err := s.archive.Save(snapshot)
+	if err != nil {
+		log.Error("failed to archive page", zap.String("url", snapshot.FinalURL), zap.Error(err))
+	}
+}
+
+// proxyClient creates an http client using the proxy of the browser.
+func proxyClient(proxyServer, proxyUsername, proxyPassword string) (*http.Client, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if proxyServer == "" || proxyUsername == "" || proxyPassword == "" {
+		return client, nil
+	}
+
+	if !strings.Contains(proxyServer, "://") {
+		proxyServer = "http://" + proxyServer
+	}
+	proxyURL, err := url.Parse(proxyServer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy server: %w", err)
+	}
+	proxyURL.User = url.UserPassword(proxyUsername, proxyPassword)
+	client.Transport = &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+
+	return client, nil
+}
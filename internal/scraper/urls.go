@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var backgroundURLPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+// documentBase returns the URL relative links of the document are resolved against.
+// It is the page URL or, if the document has one, the href of its <base> element.
+func documentBase(doc *html.Node, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	baseNode := findNode(doc, "base")
+	if baseNode == nil {
+		return base
+	}
+
+	href := strings.TrimSpace(attribute(baseNode, "href"))
+	if href == "" {
+		return base
+	}
+
+	if base == nil {
+		u, err := url.Parse(href)
+		if err != nil || !u.IsAbs() {
+			return nil
+		}
+
+		return u
+	}
+
+	u, err := base.Parse(href)
+	if err != nil {
+		return base
+	}
+
+	return u
+}
+
+// resolveURLs rewrites the href, src and srcset attributes and the CSS background images of all elements to absolute URLs.
+func resolveURLs(n *html.Node, base *url.URL) {
+	if base == nil {
+		return
+	}
+
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			switch attr.Key {
+			case "href", "src", "data-src":
+				n.Attr[i].Val = resolveURL(base, attr.Val)
+			case "srcset", "data-srcset":
+				n.Attr[i].Val = resolveSrcset(base, attr.Val)
+			case "style":
+				n.Attr[i].Val = backgroundURLPattern.ReplaceAllStringFunc(attr.Val, func(match string) string {
+					ref := backgroundURLPattern.FindStringSubmatch(match)[1]
+
+					return "url(" + resolveURL(base, ref) + ")"
+				})
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		resolveURLs(c, base)
+	}
+}
+
+// keptSchemes are the schemes of references which are no pages or images and are kept as they are.
+var keptSchemes = []string{"javascript:", "data:", "mailto:", "tel:"}
+
+// resolveURL resolves the reference against the base. Fragments and references of
+// the kept schemes, like scripts, inline data, mail and phone links, are kept as they are.
+func resolveURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || hasScheme(trimmed, keptSchemes...) {
+		return ref
+	}
+
+	u, err := base.Parse(trimmed)
+	if err != nil {
+		return ref
+	}
+
+	return u.String()
+}
+
+// resolveSrcset resolves the URL of every image candidate, e.g. "small.webp 480w, large.webp 1080w".
+func resolveSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+
+		fields[0] = resolveURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+
+	return strings.Join(candidates, ", ")
+}
+
+// hasScheme reports whether the reference starts with one of the schemes, ignoring the case.
+func hasScheme(ref string, schemes ...string) bool {
+	lower := strings.ToLower(ref)
+
+	return slices.ContainsFunc(schemes, func(scheme string) bool {
+		return strings.HasPrefix(lower, scheme)
+	})
+}
+
+func findNode(n *html.Node, tagName string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tagName {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if result := findNode(c, tagName); result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestResolveURL(t *testing.T) {
+	base, _ := url.Parse("https://escape.example.com/berlin/rooms/")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"gruft/", "https://escape.example.com/berlin/rooms/gruft/"},
+		{"../gutscheine/", "https://escape.example.com/berlin/gutscheine/"},
+		{"/buchen?room=gruft", "https://escape.example.com/buchen?room=gruft"},
+		{"//cdn.example.com/gruft.jpg", "https://cdn.example.com/gruft.jpg"},
+		{" gruft/ ", "https://escape.example.com/berlin/rooms/gruft/"},
+		{"https://bookeo.com/escape", "https://bookeo.com/escape"},
+		// references which are no pages are kept
+		{"#details", "#details"},
+		{"javascript:void(0)", "javascript:void(0)"},
+		{"JavaScript:openMenu()", "JavaScript:openMenu()"},
+		{"mailto:info@escape.example.com", "mailto:info@escape.example.com"},
+		{"tel:+4930123456", "tel:+4930123456"},
+		{"data:image/gif;base64,R0lGOD", "data:image/gif;base64,R0lGOD"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := resolveURL(base, tt.ref); got != tt.want {
+			t.Errorf("resolveURL(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestResolveURLDeduplicates(t *testing.T) {
+	base, _ := url.Parse("https://escape.example.com/berlin/rooms/")
+
+	// the spellings of one page resolve to the same URL, so the frontier skips it after the first visit
+	refs := []string{"gruft/", "./gruft/", "../rooms/gruft/", "/berlin/rooms/gruft/", "https://escape.example.com/berlin/rooms/gruft/"}
+	for _, ref := range refs {
+		if got := resolveURL(base, ref); got != "https://escape.example.com/berlin/rooms/gruft/" {
+			t.Errorf("resolveURL(%q) = %q, want the URL of the other spellings", ref, got)
+		}
+	}
+}
+
+func TestResolveURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "page url",
+			page: `<html><body><a href="gruft/">Die Gruft</a><img src="/img/gruft.jpg"></body></html>`,
+			want: `<body><a href="https://escape.example.com/rooms/gruft/">Die Gruft</a><img src="https://escape.example.com/img/gruft.jpg"/></body>`,
+		},
+		{
+			name: "base element",
+			page: `<html><head><base href="https://cdn.example.com/berlin/"></head><body><a href="gruft/">Die Gruft</a></body></html>`,
+			want: `<body><a href="https://cdn.example.com/berlin/gruft/">Die Gruft</a></body>`,
+		},
+		{
+			name: "srcset and background",
+			page: `<html><body><img srcset="small.webp 480w, /large.webp 1080w"><div style="background-image: url('bg.jpg')"></div></body></html>`,
+			want: `<body><img srcset="https://escape.example.com/rooms/small.webp 480w, https://escape.example.com/large.webp 1080w"/><div style="background-image: url(https://escape.example.com/rooms/bg.jpg)"></div></body>`,
+		},
+		{
+			name: "mail, phone and script links",
+			page: `<html><body><a href="mailto:info@escape.example.com">Mail</a><a href="tel:+4930123456">Anrufen</a><a href="javascript:void(0)">Menü</a></body></html>`,
+			want: `<body><a href="mailto:info@escape.example.com">Mail</a><a href="tel:+4930123456">Anrufen</a><a href="javascript:void(0)">Menü</a></body>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatalf("Error parsing page: %v", err)
+			}
+			body := findBodyNode(doc)
+			resolveURLs(body, documentBase(doc, "https://escape.example.com/rooms/"))
+
+			var buf strings.Builder
+			err = html.Render(&buf, body)
+			if err != nil {
+				t.Fatalf("Error rendering page: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Unexpected page\n got %s\nwant %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkTarget(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"https://escape.example.com/buchen", "https://escape.example.com/buchen"},
+		{"#top", ""},
+		{"javascript:void(0)", ""},
+		{"mailto:info@escape.example.com", ""},
+		{"TEL:+4930123456", ""},
+	}
+
+	for _, tt := range tests {
+		if got := linkTarget(tt.ref); got != tt.want {
+			t.Errorf("linkTarget(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
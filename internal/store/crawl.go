@@ -0,0 +1,119 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+)
+
+// Page is the content of a visited page as it was sent to the llm.
+type Page struct {
+	URL     string `json:"url"`
+	Content string `json:"content"`
+}
+
+// CrawlState is the resumable state of crawling one provider with one model.
+type CrawlState struct {
+	ProviderName   string      `json:"provider_name"`
+	ProviderURL    string      `json:"provider_url"`
+	Model          string      `json:"model"`
+	ContentFormat  string      `json:"content_format"`
+	ExtractionMode string      `json:"extraction_mode"`
+	VisionMode     string      `json:"vision_mode,omitempty"`
+	Done           bool        `json:"done"`
+	Error          string      `json:"error,omitempty"`
+	Visited        []string    `json:"visited"`
+	Pending        []string    `json:"pending"`
+	Pages          []Page      `json:"pages"`
+	Rooms          []llms.Room `json:"rooms"`
+
+	Conversation *llms.Conversation `json:"conversation"`
+
+	Steps                int           `json:"steps"`
+	LLMDuration          time.Duration `json:"llm_duration"`
+	RequestDuration      time.Duration `json:"request_duration"`
+	TokenCount           int           `json:"token_count"`
+	TokenLimitReached    bool          `json:"token_limit_reached"`
+	ContextCompactions   int           `json:"context_compactions"`
+	ChunksExtracted      int           `json:"chunks_extracted"`
+	ConsentBanners       int           `json:"consent_banners"`
+	InvalidURLs          int           `json:"invalid_urls"`
+	ToolRetries          int           `json:"tool_retries"`
+	WebsitesChecked      int           `json:"websites_checked"`
+	WebsiteMaxLength     int           `json:"website_max_length"`
+	WebsiteReducedLength int           `json:"website_reduced_length"`
+	UpdatedAt            time.Time     `json:"updated_at"`
+}
+
+// NewCrawlState creates the state of a crawl starting at the provider URL.
+func NewCrawlState(providerName, providerURL, model string) *CrawlState {
+	return &CrawlState{
+		ProviderName: providerName,
+		ProviderURL:  providerURL,
+		Model:        model,
+		Pending:      []string{providerURL},
+		Conversation: llms.NewConversation(),
+	}
+}
+
+// MarkVisited moves the URL from the pending URLs to the visited URLs, which hold its canonical form.
+func (c *CrawlState) MarkVisited(url, canonical string) {
+	c.Pending = slices.DeleteFunc(c.Pending, func(u string) bool {
+		return u == url
+	})
+	if !slices.Contains(c.Visited, canonical) {
+		c.Visited = append(c.Visited, canonical)
+	}
+}
+
+func crawlKey(providerURL, model string) string {
+	return providerURL + "\x00" + model
+}
+
+// CrawlState loads the state of the provider crawled with the model. It returns nil if there is none.
+func (s *Store) CrawlState(providerURL, model string) (*CrawlState, error) {
+	state := &CrawlState{}
+	ok, err := s.Get(crawlBucket, crawlKey(providerURL, model), state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load crawl state: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	if state.Conversation == nil {
+		state.Conversation = llms.NewConversation()
+	}
+
+	return state, nil
+}
+
+func (s *Store) SaveCrawlState(state *CrawlState) error {
+	state.UpdatedAt = time.Now()
+	err := s.Put(crawlBucket, crawlKey(state.ProviderURL, state.Model), state)
+	if err != nil {
+		return fmt.Errorf("failed to save crawl state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteCrawlState(providerURL, model string) error {
+	return s.Delete(crawlBucket, crawlKey(providerURL, model))
+}
+
+// SaveJob stores a job of the HTTP API under its id.
+func (s *Store) SaveJob(id string, job any) error {
+	err := s.Put(jobBucket, id, job)
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+
+	return nil
+}
+
+// Jobs calls fn with the raw JSON of every stored job.
+func (s *Store) Jobs(fn func(data []byte) error) error {
+	return s.List(jobBucket, fn)
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	crawlBucket = "crawls"
+	jobBucket   = "jobs"
+)
+
+// Store is an embedded key value store. Every bucket is a directory and every
+// key a JSON file inside of it, which is replaced atomically on write.
+type Store struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+func New(dir string) (*Store, error) {
+	for _, bucket := range []string{crawlBucket, jobBucket} {
+		err := os.MkdirAll(filepath.Join(dir, bucket), 0755)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+	}
+
+	return &Store{
+		dir: dir,
+	}, nil
+}
+
+// Put stores the JSON encoding of v under the key of the bucket.
+func (s *Store) Put(bucket, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, bucket)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to write value: %w", err)
+	}
+
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to sync value: %w", err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	err = os.Rename(file.Name(), s.path(bucket, key))
+	if err != nil {
+		return fmt.Errorf("failed to replace value: %w", err)
+	}
+
+	return nil
+}
+
+// Get decodes the value of the key into v. It reports false if the key does not exist.
+func (s *Store) Get(bucket, key string, v any) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, err := os.ReadFile(s.path(bucket, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read value: %w", err)
+	}
+
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+
+	return true, nil
+}
+
+// Delete removes the key from the bucket. Deleting a missing key is not an error.
+func (s *Store) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(bucket, key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete value: %w", err)
+	}
+
+	return nil
+}
+
+// List calls fn with the raw JSON of every value in the bucket.
+func (s *Store) List(bucket string, fn func(data []byte) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, bucket))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bucket: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, bucket, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read value: %w", err)
+		}
+
+		err = fn(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// path hashes the key, so any string (e.g. an URL) can be used as a key.
+func (s *Store) path(bucket, key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(s.dir, bucket, hex.EncodeToString(sum[:16])+".json")
+}
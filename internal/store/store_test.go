@@ -0,0 +1,55 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/internal/llms"
+	"github.com/martinbockt/esc-llm-webscraper/internal/store"
+)
+
+func TestCrawlStateRoundTrip(t *testing.T) {
+	st, err := store.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error creating store: %v", err)
+	}
+
+	state, err := st.CrawlState("https://example.com/", "model")
+	if err != nil {
+		t.Fatalf("Error loading missing state: %v", err)
+	}
+	if state != nil {
+		t.Fatalf("Expected no state, got %+v", state)
+	}
+
+	state = store.NewCrawlState("Example", "https://example.com/", "model")
+	state.Pending = append(state.Pending, "https://example.com/rooms/")
+	state.MarkVisited("https://example.com/", "https://example.com/")
+	state.Rooms = []llms.Room{{Name: "Pharaoh's Tomb"}}
+	err = st.SaveCrawlState(state)
+	if err != nil {
+		t.Fatalf("Error saving state: %v", err)
+	}
+
+	loaded, err := st.CrawlState("https://example.com/", "model")
+	if err != nil {
+		t.Fatalf("Error loading state: %v", err)
+	}
+	if len(loaded.Visited) != 1 || loaded.Visited[0] != "https://example.com/" {
+		t.Errorf("Unexpected visited URLs: %v", loaded.Visited)
+	}
+	if len(loaded.Pending) != 1 || loaded.Pending[0] != "https://example.com/rooms/" {
+		t.Errorf("Unexpected pending URLs: %v", loaded.Pending)
+	}
+	if len(loaded.Rooms) != 1 || loaded.Rooms[0].Name != "Pharaoh's Tomb" {
+		t.Errorf("Unexpected rooms: %v", loaded.Rooms)
+	}
+
+	err = st.DeleteCrawlState("https://example.com/", "model")
+	if err != nil {
+		t.Fatalf("Error deleting state: %v", err)
+	}
+	loaded, err = st.CrawlState("https://example.com/", "model")
+	if err != nil || loaded != nil {
+		t.Fatalf("Expected deleted state, got %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,334 @@
+// Package httpreplay records HTTP interactions of the llm clients to a file and replays them deterministically.
+//
+// The Recorder and the Replayer are http.RoundTrippers for clients accepting an
+// *http.Client. Clients which only accept a base URL, e.g. the langchaingo mistral
+// client, can be pointed to a local server running the Handler of a Replayer.
+// The Vertex AI client talks gRPC and can't be recorded, replayed it gets the
+// http client over REST instead.
+package httpreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNoInteraction is returned by the Replayer if no recorded interaction matches the request.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds the fields of a request used for matching. Headers are not recorded,
+// so no API keys end up in the fixture files.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body
+}
+
+// Body keeps JSON bodies readable in the fixture files, other bodies are stored as text.
+// JSON bodies are replayed compacted.
+type Body struct {
+	JSON json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+func newBody(data []byte) Body {
+	if len(data) == 0 {
+		return Body{}
+	}
+	if json.Valid(data) {
+		return Body{JSON: compact(data)}
+	}
+
+	return Body{Text: string(data)}
+}
+
+func (b Body) bytes() []byte {
+	if b.JSON != nil {
+		return compact(b.JSON)
+	}
+
+	return []byte(b.Text)
+}
+
+// Recorder records the interactions of the wrapped transport.
+type Recorder struct {
+	transport http.RoundTripper
+	// filename is the file the interactions are saved to after every interaction, if set
+	filename     string
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithFile saves the interactions to the file after every interaction,
+// so they are kept if the process doesn't exit normally.
+func WithFile(filename string) Option {
+	return func(r *Recorder) {
+		r.filename = filename
+	}
+}
+
+// NewRecorder creates a recorder sending the requests with the transport, http.DefaultTransport if nil.
+func NewRecorder(transport http.RoundTripper, opts ...Option) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		transport: transport,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// RoundTrip sends the request and records it with its response.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions = append(r.interactions, Interaction{
+		Request: request,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     responseHeader(resp.Header),
+			Body:       newBody(body),
+		},
+	})
+	if r.filename != "" {
+		err = r.save(r.filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the file.
+func (r *Recorder) Save(filename string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.save(filename)
+}
+
+// save writes the interactions to the file. The caller holds the lock.
+func (r *Recorder) save(filename string) error {
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal interactions: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	err = os.Rename(tmp, filename)
+	if err != nil {
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	return nil
+}
+
+// Replayer answers requests with recorded responses.
+// A request matches the first unused interaction with the same method, path and body.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Load reads the interactions of a file written by Recorder.Save.
+func Load(filename string) (*Replayer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var interactions []Interaction
+	err = json.Unmarshal(data, &interactions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal interactions: %w", err)
+	}
+
+	return NewReplayer(interactions), nil
+}
+
+// NewReplayer creates a replayer of the interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+	}
+}
+
+// RoundTrip returns the recorded response of the request without sending it.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || !interaction.Request.matches(request) {
+			continue
+		}
+		r.used[i] = true
+
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+
+		body := interaction.Response.bytes()
+
+		return &http.Response{
+			Status:        http.StatusText(interaction.Response.StatusCode),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, request.Method, request.Path)
+}
+
+// Handler serves the recorded responses, e.g. as local stand-in for an llm API.
+func (r *Replayer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp, err := r.RoundTrip(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+		defer resp.Body.Close()
+
+		for key, values := range resp.Header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
+
+// Unused returns the number of interactions which weren't replayed yet.
+func (r *Replayer) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := 0
+	for _, used := range r.used {
+		if !used {
+			unused++
+		}
+	}
+
+	return unused
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	request := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+	if req.URL.RawQuery != "" {
+		request.Path += "?" + req.URL.RawQuery
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return request, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return Request{}, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	request.Body = newBody(body)
+
+	return request, nil
+}
+
+func (r Request) matches(other Request) bool {
+	return r.Method == other.Method && r.Path == other.Path && bytes.Equal(r.bytes(), other.bytes())
+}
+
+func compact(body []byte) []byte {
+	var buf bytes.Buffer
+	if json.Compact(&buf, body) != nil {
+		return body
+	}
+
+	return buf.Bytes()
+}
+
+// responseHeader keeps the content type of a response. Bodies are recorded decoded,
+// so the other headers, e.g. the content encoding, don't apply to a replayed response.
+func responseHeader(header http.Header) http.Header {
+	kept := http.Header{}
+	if value := header.Get("Content-Type"); value != "" {
+		kept.Set("Content-Type", value)
+	}
+
+	return kept
+}
@@ -0,0 +1,93 @@
+package httpreplay_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martinbockt/esc-llm-webscraper/pkg/httpreplay"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	recorder := httpreplay.NewRecorder(nil)
+	client := &http.Client{Transport: recorder}
+	post(t, client, server.URL+"/v1/chat/completions", `{"model": "gpt-4o-mini"}`)
+
+	filename := filepath.Join(t.TempDir(), "fixtures.json")
+	err := recorder.Save(filename)
+	if err != nil {
+		t.Fatalf("Error saving interactions: %v", err)
+	}
+
+	replayer, err := httpreplay.Load(filename)
+	if err != nil {
+		t.Fatalf("Error loading interactions: %v", err)
+	}
+
+	// the replayer matches the path and the body, not the host
+	standIn := httptest.NewServer(replayer.Handler())
+	defer standIn.Close()
+	got := post(t, http.DefaultClient, standIn.URL+"/v1/chat/completions", `{"model":"gpt-4o-mini"}`)
+	// recorded JSON bodies are replayed compacted
+	if want := `{"echo":{"model":"gpt-4o-mini"}}`; got != want {
+		t.Errorf("Expected replayed body %q, got %q", want, got)
+	}
+	if replayer.Unused() != 0 {
+		t.Errorf("Expected all interactions to be used, %d are left", replayer.Unused())
+	}
+
+	client = &http.Client{Transport: replayer}
+	_, err = client.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"gpt-4o-mini"}`))
+	if !errors.Is(err, httpreplay.ErrNoInteraction) {
+		t.Errorf("Expected ErrNoInteraction for a used interaction, got %v", err)
+	}
+}
+
+func TestRecorderWithFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// the interactions are saved without calling Save, e.g. if the process is killed
+	filename := filepath.Join(t.TempDir(), "fixtures.json")
+	client := &http.Client{Transport: httpreplay.NewRecorder(nil, httpreplay.WithFile(filename))}
+	post(t, client, server.URL+"/v1/messages", `{"n": 1}`)
+	post(t, client, server.URL+"/v1/messages", `{"n": 2}`)
+
+	replayer, err := httpreplay.Load(filename)
+	if err != nil {
+		t.Fatalf("Error loading interactions: %v", err)
+	}
+	if replayer.Unused() != 2 {
+		t.Errorf("Expected 2 saved interactions, got %d", replayer.Unused())
+	}
+}
+
+func post(t *testing.T, client *http.Client, url, body string) string {
+	t.Helper()
+
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading response: %v", err)
+	}
+
+	return string(data)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -20,11 +21,35 @@ type client struct {
 	client  *http.Client
 }
 
-// NewClient initializes a new TogetherAI client.
-func newClient(logger *zap.Logger, apiKey string) *client {
-	return &client{
+// DefaultBaseURL is the URL of the Jamba API.
+const DefaultBaseURL = "https://api.ai21.com/studio"
+
+// Option configures the client.
+type Option func(*client)
+
+// WithBaseURL sets the URL of the API, e.g. of a local stand-in server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimSuffix(baseURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client sending the requests, e.g. with a recording transport.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *client) {
+		if httpClient != nil {
+			c.client = httpClient
+		}
+	}
+}
+
+// NewClient initializes a new Jamba client.
+func newClient(logger *zap.Logger, apiKey string, opts ...Option) *client {
+	c := &client{
 		apiKey:  apiKey,
-		baseURL: "https://api.ai21.com/studio",
+		baseURL: DefaultBaseURL,
 		logger:  logger,
 		client:  &http.Client{
 			// Timeout: 120 * time.Second,
@@ -34,6 +59,11 @@ func newClient(logger *zap.Logger, apiKey string) *client {
 			// },
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // handleResponse handles the HTTP response and decodes the JSON into the response interface.
@@ -142,8 +142,8 @@ type FunctionResponse struct {
 }
 
 // NewChatService initializes a new ChatService.
-func NewChatService(logger *zap.Logger, apiKey string) *ChatService {
-	return &ChatService{client: newClient(logger, apiKey)}
+func NewChatService(logger *zap.Logger, apiKey string, opts ...Option) *ChatService {
+	return &ChatService{client: newClient(logger, apiKey, opts...)}
 }
 
 // CreateChatCompletion creates a chat completion using the TogetherAI API.